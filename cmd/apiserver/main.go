@@ -1,20 +1,32 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/lib/pq"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/handler/api"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/migrate"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/sql/schema"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
 func main() {
 	godotenv.Load(".env")
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	handlerFunc, err := api.NewApiConfig()
 	if err != nil {
 		log.Fatal(err)
@@ -23,7 +35,7 @@ func main() {
 	defer handlerFunc.Server.DB.Close()
 
 	e := echo.New()
-	e.Validator = utils.NewCustomValidator()
+	e.Validator = utils.NewCustomValidator(handlerFunc.Catalog)
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(50)))
@@ -39,7 +51,9 @@ func main() {
 	}))
 
 	routerV1 := e.Group("/api/v1")
+	routerV1.Use(handlerFunc.MiddlewareAuth)
 	routerV1.GET("/health", handlerFunc.HandlerHealth)
+	routerV1.POST("/auth/token", handlerFunc.HandlerIssueToken)
 	routerV1.GET("/students", handlerFunc.HandlerGetStudents)
 	routerV1.POST("/students/create",
 		handlerFunc.HandlerCreateStudent,
@@ -52,3 +66,15 @@ func main() {
 
 	e.Logger.Fatal(e.Start(":" + "3000"))
 }
+
+// runMigrate is the "migrate up|down|status" subcommand, it applies the
+// schema embedded in the binary
+func runMigrate(args []string) error {
+	s, err := server.GetServerConfig()
+	if err != nil {
+		return err
+	}
+	defer s.DB.Close()
+
+	return migrate.Command(context.Background(), s.DB, schema.FS, args, os.Stdout)
+}
@@ -0,0 +1,58 @@
+// hashbench times what the login spends on the password: one verify of
+// the old bcrypt cost 14 hash against the hasher configured by the
+// environment (password_hasher, argon2_*, bcrypt_cost). Run it on the
+// server the app runs on, the numbers depend on the machine
+//
+//	go run ./cmd/hashbench -n 10
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/password"
+)
+
+const samplePassword = "Sample-Password-123"
+
+func main() {
+	n := flag.Int("n", 5, "verifications per hasher")
+	flag.Parse()
+
+	godotenv.Load()
+
+	current, err := password.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	before := password.NewManager(password.DefaultBcrypt)
+
+	legacy := measure("bcrypt cost 14 (before)", before, *n)
+	now := measure("configured hasher (now)", current, *n)
+
+	fmt.Printf("\nlogin verify is %.1fx faster\n", float64(legacy)/float64(now))
+}
+
+// measure is the average time of one Verify, the time of Hash is close
+// to it as both run the key derivation once
+func measure(name string, manager *password.Manager, n int) time.Duration {
+	hash, err := manager.Hash(samplePassword)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	start := time.Now()
+	for range n {
+		if ok, _, err := manager.Verify(samplePassword, hash); err != nil || !ok {
+			log.Fatalf("%v: verify failed, %v", name, err)
+		}
+	}
+	avg := time.Since(start) / time.Duration(n)
+
+	fmt.Printf("%-26s %-12v %v\n", name, avg, hash[:min(len(hash), 32)]+"...")
+	return avg
+}
@@ -0,0 +1,305 @@
+// mockidp is a tiny OpenID Connect provider for trying the single sign-on
+// locally, nothing here is meant for production. The sign in form takes
+// any email & groups, point the webserver at it with
+//
+//	oidc_issuer=http://localhost:9000
+//	oidc_client_id=rambanbelajar
+//	oidc_redirect_url=http://localhost:8080/login/sso/callback
+//	oidc_role_map=teachers=teacher,staff=admin
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	keyID   = "mockidp-1"
+	codeTTL = time.Minute
+)
+
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	challenge     string
+	nonce         string
+	email         string
+	emailVerified bool
+	groups        []string
+	expireAt      time.Time
+}
+
+type provider struct {
+	issuer   string
+	clientID string
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+var signInPage = template.Must(template.New("signin").Parse(`<!DOCTYPE html>
+<html>
+  <head><meta charset="utf-8"><title>mockidp</title></head>
+  <body style="font-family: sans-serif; max-width: 28rem; margin: 4rem auto">
+    <h2>mockidp sign in</h2>
+    <form method="POST" action="/authorize">
+      {{ range $k, $v := .Query }}<input type="hidden" name="{{ $k }}" value="{{ index $v 0 }}">{{ end }}
+      <p><label>Email<br><input type="email" name="email" required autofocus></label></p>
+      <p><label><input type="checkbox" name="email_verified" value="true" checked> email verified</label></p>
+      <p><label>Groups (comma separated, empty for no groups claim)<br><input name="groups"></label></p>
+      <p><button type="submit">Sign in</button> <button type="submit" name="deny" value="1">Deny</button></p>
+    </form>
+  </body>
+</html>`))
+
+func main() {
+	godotenv.Load(".env")
+
+	addr := os.Getenv("mockidp_addr")
+	if addr == "" {
+		addr = ":9000"
+	}
+
+	issuer := os.Getenv("mockidp_issuer")
+	if issuer == "" {
+		issuer = "http://localhost" + addr
+	}
+
+	clientID := os.Getenv("oidc_client_id")
+	if clientID == "" {
+		clientID = "rambanbelajar"
+	}
+
+	// a fresh key on every start, the relying party refetches the keys on
+	// the unknown key id anyway
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := &provider{
+		issuer:   strings.TrimRight(issuer, "/"),
+		clientID: clientID,
+		key:      key,
+		codes:    map[string]authCode{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("GET /authorize", p.authorizeForm)
+	mux.HandleFunc("POST /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+
+	log.Printf("mockidp: issuer %v, client %v, listening on %v", p.issuer, p.clientID, addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *provider) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.clientID || q.Get("response_type") != "code" {
+		http.Error(w, "unknown client or response type", http.StatusBadRequest)
+		return
+	}
+
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	signInPage.Execute(w, map[string]any{"Query": q})
+}
+
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil || r.PostForm.Get("client_id") != p.clientID {
+		http.Error(w, "bad redirect_uri or client_id", http.StatusBadRequest)
+		return
+	}
+
+	back := redirectURI.Query()
+	back.Set("state", r.PostForm.Get("state"))
+
+	if r.PostForm.Get("deny") != "" {
+		back.Set("error", "access_denied")
+		back.Set("error_description", "the user denied the sign in")
+		redirectURI.RawQuery = back.Encode()
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+		return
+	}
+
+	var groups []string
+	if raw := strings.TrimSpace(r.PostForm.Get("groups")); raw != "" {
+		groups = []string{}
+		for _, g := range strings.Split(raw, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	code := randomString()
+
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		clientID:      p.clientID,
+		redirectURI:   r.PostForm.Get("redirect_uri"),
+		challenge:     r.PostForm.Get("code_challenge"),
+		nonce:         r.PostForm.Get("nonce"),
+		email:         strings.ToLower(strings.TrimSpace(r.PostForm.Get("email"))),
+		emailVerified: r.PostForm.Get("email_verified") == "true",
+		groups:        groups,
+		expireAt:      time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	back.Set("code", code)
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	// the code is single use, taken out before anything is checked
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(code.expireAt) ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != code.clientID ||
+		r.PostForm.Get("redirect_uri") != code.redirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if b64(verifier[:]) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	// the subject stays the same for the same email, like a real account
+	sub := sha256.Sum256([]byte(code.email))
+	now := time.Now()
+
+	claims := map[string]any{
+		"iss":            p.issuer,
+		"aud":            code.clientID,
+		"sub":            b64(sub[:12]),
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.email,
+		"email_verified": code.emailVerified,
+		"name":           strings.Split(code.email, "@")[0],
+	}
+	if code.groups != nil {
+		claims["groups"] = code.groups
+	}
+
+	idToken, err := p.sign(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *provider) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + b64(sig), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b64(b)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"html/template"
 	"io"
 	"log"
@@ -11,6 +12,10 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/lib/pq"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/handler/web"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/migrate"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/queue"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/sql/schema"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
@@ -34,6 +39,13 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	portStr := os.Getenv("port")
 	if portStr == "" {
 		log.Fatal("error: couldn't find the port in environment")
@@ -54,7 +66,7 @@ func main() {
 
 	// global set up
 	e.Use(middleware.Logger())
-	e.Validator = utils.NewCustomValidator()
+	e.Validator = utils.NewCustomValidator(webCfg.Catalog)
 	e.Renderer = newTemplate()
 	e.Static("/static", "static")
 
@@ -79,6 +91,16 @@ func main() {
 	mainRoute.GET("/login", webCfg.GetLoginPage)
 	mainRoute.POST("/login", webCfg.Login("/", utils.USER_ROLE_STUDENT))
 	mainRoute.POST("/logout", webCfg.Logout("/login"))
+	mainRoute.GET("/forgot-password", webCfg.GetForgotPasswordPage(utils.USER_ROLE_STUDENT))
+	mainRoute.POST("/forgot-password", webCfg.ForgotPassword(utils.USER_ROLE_STUDENT))
+	mainRoute.GET("/reset-password", webCfg.GetResetPasswordPage)
+	mainRoute.POST("/reset-password", webCfg.ResetPassword)
+	mainRoute.GET("/verify-email", webCfg.GetVerifyEmailPage)
+	mainRoute.POST("/verify-email/resend", webCfg.ResendEmailVerification)
+	mainRoute.GET("/login/2fa", webCfg.GetTwoFactorPage(utils.USER_ROLE_STUDENT))
+	mainRoute.POST("/login/2fa", webCfg.VerifyTwoFactor(utils.USER_ROLE_STUDENT))
+	mainRoute.GET("/login/sso", webCfg.StartSSO(utils.USER_ROLE_STUDENT))
+	mainRoute.GET("/login/sso/callback", webCfg.SSOCallback)
 
 	mainRoute.GET("/", webCfg.GetHomePage)
 
@@ -86,22 +108,94 @@ func main() {
 	mainRoute.GET("/students/:id/profile/update", webCfg.GetUpdateStudentPage)
 	mainRoute.PUT("/students/:id/profile/update", webCfg.UpdateStudent)
 
+	mainRoute.GET("/study-plan", webCfg.GetStudyPlanPage)
+	mainRoute.POST("/study-plan/courses/:id/enroll", webCfg.EnrollCourse)
+	mainRoute.PUT("/study-plan/courses/:id/drop", webCfg.DropCourse)
+
+	mainRoute.GET("/teachers/:id/profile", webCfg.GetTeacherProfile)
+
+	mainRoute.GET("/account/role", webCfg.GetRoleSwitcher)
+	mainRoute.POST("/account/role", webCfg.SwitchActiveRole)
+	mainRoute.GET("/account/password", webCfg.GetChangePasswordPage)
+	mainRoute.POST("/account/password", webCfg.ChangePassword)
+	mainRoute.GET("/impersonation/banner", webCfg.GetImpersonationBanner)
+	mainRoute.POST("/impersonation/stop", webCfg.StopImpersonation)
+	mainRoute.GET("/account/security", webCfg.GetAccountSecurityPage)
+	mainRoute.POST("/account/2fa/setup", webCfg.SetupTwoFactor)
+	mainRoute.POST("/account/2fa/enable", webCfg.EnableTwoFactor)
+	mainRoute.POST("/account/2fa/disable", webCfg.DisableTwoFactor)
+	mainRoute.POST("/account/2fa/recovery-codes", webCfg.RegenerateRecoveryCodes)
+	mainRoute.GET("/account/sessions", webCfg.GetMySessionsPage)
+	mainRoute.POST("/account/sessions/revoke-others", webCfg.RevokeMyOtherSessions)
+	mainRoute.POST("/account/sessions/:id/revoke", webCfg.RevokeMySession)
+	mainRoute.GET("/account/api-keys", webCfg.GetAPIKeysPage)
+	mainRoute.POST("/account/api-keys/create", webCfg.CreateAPIKey)
+	mainRoute.POST("/account/api-keys/:id/revoke", webCfg.RevokeAPIKey)
+
+	mainRoute.GET("/courses", webCfg.GetCoursePage)
+	mainRoute.POST("/courses/create", webCfg.CreateCourse)
+	mainRoute.GET("/courses/:id/update", webCfg.GetUpdateCoursePage)
+	mainRoute.PUT("/courses/:id/update", webCfg.UpdateCourse)
+	mainRoute.PUT("/courses/:id/archive", webCfg.ArchiveCourse)
+	mainRoute.GET("/courses/:id/download", webCfg.DownloadCourse)
+
 	// admin route
 	adminRoute := mainRoute.Group("/admin")
 	adminRoute.GET("/login", webCfg.GetAdminLoginPage)
 	adminRoute.POST("/login", webCfg.Login("/admin/panel", utils.USER_ROLE_ADMIN))
 	adminRoute.POST("/logout", webCfg.Logout("/admin/login"))
+	adminRoute.GET("/forgot-password", webCfg.GetForgotPasswordPage(utils.USER_ROLE_ADMIN))
+	adminRoute.POST("/forgot-password", webCfg.ForgotPassword(utils.USER_ROLE_ADMIN))
+	adminRoute.GET("/login/2fa", webCfg.GetTwoFactorPage(utils.USER_ROLE_ADMIN))
+	adminRoute.POST("/login/2fa", webCfg.VerifyTwoFactor(utils.USER_ROLE_ADMIN))
+	adminRoute.GET("/login/sso", webCfg.StartSSO(utils.USER_ROLE_ADMIN))
 
 	adminRoute.GET("/panel", webCfg.GetAdminPanelPage)
 
 	adminRoute.GET("/panel/users", webCfg.GetUsersPage)
 	adminRoute.POST("/panel/users/create", webCfg.CreateUser)
+	adminRoute.GET("/panel/users/:id/sessions", webCfg.GetUserSessionsPage)
+	adminRoute.PUT("/panel/users/:id/sessions/revoke-all", webCfg.RevokeAllUserSessions)
+	adminRoute.PUT("/panel/users/:id/sessions/:sessionId/revoke", webCfg.RevokeUserSession)
+	adminRoute.PUT("/panel/users/:id/password/require-change", webCfg.RequirePasswordChange)
 
 	adminRoute.GET("/panel/students", webCfg.GetStudentsPage)
 	adminRoute.GET("/panel/students/create", webCfg.GetStudentSubmitPage)
 	adminRoute.POST("/panel/students/create", webCfg.CreateStudent, webCfg.MiddlewareStudent)
 	adminRoute.GET("/panel/students/:id/view", webCfg.GetStudentProfile)
 	adminRoute.DELETE("/panel/students/:id/delete", webCfg.DeleteStudent)
+	adminRoute.POST("/panel/students/:id/impersonate", webCfg.StartImpersonation)
+
+	adminRoute.GET("/panel/teachers", webCfg.GetTeachersPage)
+	adminRoute.GET("/panel/teachers/create", webCfg.GetTeacherSubmitPage)
+	adminRoute.POST("/panel/teachers/create", webCfg.CreateTeacher)
+	adminRoute.GET("/panel/teachers/:id/view", webCfg.GetTeacherProfile)
+	adminRoute.DELETE("/panel/teachers/:id/delete", webCfg.DeleteTeacher)
+
+	adminRoute.GET("/panel/majors", webCfg.GetMajorsPage)
+	adminRoute.POST("/panel/majors/create", webCfg.CreateMajor)
+	adminRoute.DELETE("/panel/majors/:id/delete", webCfg.DeleteMajor)
+	adminRoute.POST("/panel/rooms/create", webCfg.CreateRoom)
+	adminRoute.PUT("/panel/rooms/:id/update", webCfg.UpdateRoom)
+	adminRoute.DELETE("/panel/rooms/:id/delete", webCfg.DeleteRoom)
+
+	adminRoute.GET("/panel/study-plans", webCfg.GetStudyPlansPage)
+	adminRoute.POST("/panel/study-plans/:id/courses", webCfg.AddStudyPlanCourse)
+	adminRoute.DELETE("/panel/study-plans/:id/courses/:courseId", webCfg.RemoveStudyPlanCourse)
+	adminRoute.POST("/panel/study-plans/:id/promote", webCfg.PromoteStudyPlan)
+
+	adminRoute.GET("/panel/jobs", webCfg.GetJobsPage)
+	adminRoute.PUT("/panel/jobs/:id/requeue", webCfg.RequeueJob)
+
+	adminRoute.GET("/panel/security", webCfg.GetSecurityPage)
+	adminRoute.PUT("/panel/security/roles/:role/totp", webCfg.UpdateRolePolicyTOTP)
+	adminRoute.PUT("/panel/security/lockouts/:id/unlock", webCfg.UnlockLoginAccount)
+	adminRoute.GET("/panel/permissions", webCfg.GetPermissionsPage)
+	adminRoute.POST("/panel/permissions/roles/create", webCfg.CreateRole)
+	adminRoute.DELETE("/panel/permissions/roles/:role/delete", webCfg.DeleteRole)
+	adminRoute.POST("/panel/permissions/roles/:role/permissions/create", webCfg.AddRolePermission)
+	adminRoute.PUT("/panel/permissions/roles/:role/permissions/remove", webCfg.RemoveRolePermission)
+	adminRoute.GET("/panel/audit", webCfg.GetAuditPage)
 
 	// SPAWN LIMITER CONTAINERS CLEANUP GOROUTINE
 	utils.CleanupLimiterContainersWatcher()
@@ -109,5 +203,22 @@ func main() {
 	// SPAWN STALE USER SESSION CLEANER
 	webCfg.Server.CleanStaleUserSessions()
 
+	// SPAWN JOB QUEUE WORKERS
+	jobQueue := queue.NewQueue(webCfg.Server)
+	jobQueue.Register(queue.JOB_KIND_COURSE_UPLOAD, queue.ProcessCourseUpload(webCfg.Server))
+	jobQueue.Start(context.Background(), 2)
+
 	e.Logger.Fatal(e.Start(":" + portStr))
 }
+
+// runMigrate is the "migrate up|down|status" subcommand, it applies the
+// schema embedded in the binary
+func runMigrate(args []string) error {
+	s, err := server.GetServerConfig()
+	if err != nil {
+		return err
+	}
+	defer s.DB.Close()
+
+	return migrate.Command(context.Background(), s.DB, schema.FS, args, os.Stdout)
+}
@@ -5,23 +5,27 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
-var ERROR_CLASS_FULL = "cannot assign to the major, due to the full student"
 var ERROR_INVALID_NIP = "error: invalid nomer induk pengguna (nip), please check your birthdate/nip"
 
+var (
+	ERROR_MISSING_BEARER_TOKEN = "error: the request needs the Authorization: Bearer <token> header"
+	ERROR_FORBIDDEN_SCOPE      = "error: the token is not allowed to do this, check the scopes of the api key"
+)
+
 type apiConfig struct {
-	Server *server.Server
+	Server  *server.Server
+	Catalog *utils.Catalog
 }
 
 type studentData struct {
 	StudyPlan database.StudyPlan
-	Room      database.Room
 }
 
 func NewApiConfig() (*apiConfig, error) {
@@ -31,16 +35,17 @@ func NewApiConfig() (*apiConfig, error) {
 	}
 
 	return &apiConfig{
-		Server: server,
+		Server:  server,
+		Catalog: utils.NewCatalog(server.Queries),
 	}, nil
 }
 
 func (config *apiConfig) HandlerMiddlewareStudent(next echo.HandlerFunc) echo.HandlerFunc {
-	var major struct {
-		Major string `json:"major"`
-	}
-
 	return func(c echo.Context) error {
+		var major struct {
+			Major string `json:"major"`
+		}
+
 		ctx := c.Request().Context()
 		q := config.Server.Queries
 
@@ -62,16 +67,6 @@ func (config *apiConfig) HandlerMiddlewareStudent(next echo.HandlerFunc) echo.Ha
 			})
 		}
 
-		var roomPrefix string
-		switch major.Major {
-		case "TEKNIK INFORMATIKA":
-			roomPrefix = "TI"
-		case "REKAYASA PERANGKAT LUNAK":
-			roomPrefix = "RPL"
-		case "AKUNTANSI":
-			roomPrefix = "AK"
-		}
-
 		studyPlan, err := q.GetStudyPlan(ctx, database.GetStudyPlanParams{
 			Semester: int32(1),
 			Major:    major.Major,
@@ -84,30 +79,7 @@ func (config *apiConfig) HandlerMiddlewareStudent(next echo.HandlerFunc) echo.Ha
 			})
 		}
 
-		pattern := "%" + roomPrefix + "%"
-		rooms, err := q.GetStudentRoom(ctx, pattern)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, Data{
-				"error": err.Error(),
-				"hint":  "here daddy, line 91",
-			})
-		}
-
-		var room database.Room
-		studentClassCount := major.Major + "-StudentCount"
-		studentCount, _ := q.GetCollectionMetaValue(ctx, studentClassCount)
-		n, _ := strconv.Atoi(studentCount)
-		if n > 10 {
-			return c.JSON(http.StatusBadRequest, Data{"error": ERROR_CLASS_FULL})
-		}
-		if n < 5 {
-			room = rooms[0]
-		}
-		if n >= 5 && n < 11 {
-			room = rooms[1]
-		}
-
-		c.Set("studentInfo", &studentData{StudyPlan: studyPlan, Room: room})
+		c.Set("studentInfo", &studentData{StudyPlan: studyPlan})
 
 		return next(c)
 	}
@@ -1,20 +1,55 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
+var errAdminExists = errors.New(ERROR_MISSING_BEARER_TOKEN)
+
+// HandlerCreateUserAdmin needs users:create, except for the very first
+// admin: without any admin nobody could hold the token to create one
 func (config *apiConfig) HandlerCreateUserAdmin(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if ok {
+		if allowed, _ := config.Server.Can(claims, "users", "create"); !allowed {
+			return c.JSON(http.StatusForbidden, Data{"error": ERROR_FORBIDDEN_SCOPE})
+		}
+	}
+	bootstrap := !ok
+
 	var reqBody struct {
 		Email    string `json:"email" validate:"email_constraints"`
 		Password string `json:"password" validate:"password_constraints"`
 	}
 
+	var verifyToken string
 	err := utils.WithTX(c.Request().Context(), config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		// two first admins at once would both count none, the lock holds
+		// the second one until the first commits, then it counts again
+		if bootstrap {
+			if err := qtx.LockAdminBootstrap(ctx); err != nil {
+				return err
+			}
+
+			admins, err := qtx.CountUsersByRole(ctx, utils.USER_ROLE_ADMIN)
+			if err != nil {
+				return err
+			}
+
+			if admins > 0 {
+				return errAdminExists
+			}
+		}
+
 		if err := c.Bind(&reqBody); err != nil {
 			return err
 		}
@@ -23,12 +58,12 @@ func (config *apiConfig) HandlerCreateUserAdmin(c echo.Context) error {
 			return err
 		}
 
-		passwordHashed, err := utils.HashPassword(reqBody.Password)
+		passwordHashed, err := config.Server.Passwords.Hash(reqBody.Password)
 		if err != nil {
 			return err
 		}
 
-		user, err := config.Server.Queries.CreateUser(c.Request().Context(), database.CreateUserParams{
+		user, err := qtx.CreateUser(c.Request().Context(), database.CreateUserParams{
 			Email:        reqBody.Email,
 			PasswordHash: passwordHashed,
 		})
@@ -36,7 +71,12 @@ func (config *apiConfig) HandlerCreateUserAdmin(c echo.Context) error {
 			return err
 		}
 
-		_, err = config.Server.Queries.CreateUserRoles(c.Request().Context(), database.CreateUserRolesParams{
+		verifyToken, err = utils.NewEmailVerification(ctx, qtx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = qtx.CreateUserRoles(c.Request().Context(), database.CreateUserRolesParams{
 			UserID: user.ID,
 			Role:   utils.USER_ROLE_ADMIN,
 		})
@@ -44,11 +84,24 @@ func (config *apiConfig) HandlerCreateUserAdmin(c echo.Context) error {
 			return err
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_USER_CREATE,
+			TargetType: "user",
+			TargetID:   user.ID.String(),
+			After:      Data{"Email": user.Email, "Role": utils.USER_ROLE_ADMIN},
+		})
 	})
+	if errors.Is(err, errAdminExists) {
+		return c.JSON(http.StatusUnauthorized, Data{"error": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, Data{"message": err.Error()})
 	}
 
+	// a failed mail doesn't undo the account, the login offers a new link
+	if err := utils.SendEmailVerification(ctx, config.Server.Mailer, utils.AppURL(c), reqBody.Email, verifyToken); err != nil {
+		log.Println("EMAIL VERIFICATION MAIL FAILED:", err)
+	}
+
 	return c.JSON(http.StatusCreated, Data{"message": "User Created, Successfuly"})
 }
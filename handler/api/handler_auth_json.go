@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// HandlerIssueToken trades the api key for the short lived bearer token
+func (config *apiConfig) HandlerIssueToken(c echo.Context) error {
+	var reqBody struct {
+		APIKey string `json:"api_key" validate:"required,max=128"`
+	}
+
+	if err := c.Bind(&reqBody); err != nil {
+		return c.JSON(http.StatusBadRequest, Data{"error": err.Error()})
+	}
+
+	if err := c.Validate(&reqBody); err != nil {
+		return c.JSON(http.StatusBadRequest, Data{"error": err.Error()})
+	}
+
+	token, apiKey, err := config.Server.ExchangeAPIKey(
+		c.Request().Context(), strings.TrimSpace(reqBody.APIKey), utils.API_TOKEN_TTL,
+	)
+	if err != nil {
+		if errors.Is(err, server.ErrInvalidAPIKey) {
+			return c.JSON(http.StatusUnauthorized, Data{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, Data{"error": err.Error()})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, Data{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.API_TOKEN_TTL.Seconds()),
+		"scope":        strings.Join(apiKey.Scopes, " "),
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -12,10 +11,20 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
 func (config *apiConfig) HandlerGetStudents(c echo.Context) error {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, Data{"error": ERROR_MISSING_BEARER_TOKEN})
+	}
+
+	if allowed, _ := config.Server.Can(claims, "students", "view"); !allowed {
+		return c.JSON(http.StatusForbidden, Data{"error": ERROR_FORBIDDEN_SCOPE})
+	}
+
 	ctx := c.Request().Context()
 	q := config.Server.Queries
 
@@ -45,6 +54,11 @@ func (config *apiConfig) HandlerGetStudents(c echo.Context) error {
 }
 
 func (config *apiConfig) HandlerGetStudentByID(c echo.Context) error {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, Data{"error": ERROR_MISSING_BEARER_TOKEN})
+	}
+
 	ctx := c.Request().Context()
 	qtx := config.Server.Queries
 	var param struct {
@@ -69,10 +83,37 @@ func (config *apiConfig) HandlerGetStudentByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, Data{"error": err.Error()})
 	}
 
+	room, err := qtx.GetStudentRoomById(ctx, student.RoomID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Data{"error": err.Error()})
+	}
+
+	allowed, err := config.Server.Authorize(ctx, claims, "students", "view", server.Target{
+		OwnerID: student.UserID,
+		RoomID:  student.RoomID,
+		Major:   room.Major,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Data{"error": err.Error()})
+	}
+
+	if !allowed {
+		return c.JSON(http.StatusForbidden, Data{"error": ERROR_FORBIDDEN_SCOPE})
+	}
+
 	return c.JSON(http.StatusOK, studentJSONFormat(student))
 }
 
 func (config *apiConfig) HandlerCreateStudent(c echo.Context) error {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, Data{"error": ERROR_MISSING_BEARER_TOKEN})
+	}
+
+	if allowed, _ := config.Server.Can(claims, "students", "create"); !allowed {
+		return c.JSON(http.StatusForbidden, Data{"error": ERROR_FORBIDDEN_SCOPE})
+	}
+
 	ctx := c.Request().Context()
 	qtx := config.Server.Queries
 
@@ -105,33 +146,30 @@ func (config *apiConfig) HandlerCreateStudent(c echo.Context) error {
 			return errors.New(utils.ERROR_INVALID_NIP)
 		}
 
-		// if free nim exists, get the smallest nim for the new created student
-		// and delete the record points to that free nim
-		nim, err := qtx.GetFreelistNim(ctx)
-		// checks if there is no free nim to be used
-		// simply generate from the student-nim
+		studentData := c.Get("studentInfo").(*studentData)
+		year := time.Now().Year()
+
+		nim, err := server.AllocateNim(ctx, qtx, studentData.StudyPlan.Major, year)
 		if err != nil {
-			nim, _ = qtx.GetCollectionMetaValue(ctx, "student-nim")
-			qtx.IncrementValueByname(ctx, "student-nim")
-			log.Println("\n\nstudent creation process...")
+			return err
 		}
 
-		err = qtx.DeleteFreelistNim(ctx, nim)
+		// least full room of the major, locked until the tx ends
+		room, err := server.AssignRoom(ctx, qtx, studentData.StudyPlan.Major)
 		if err != nil {
-			return fmt.Errorf("here daddy 87, %v", err.Error())
+			return err
 		}
 
-		studentData := c.Get("studentInfo").(*studentData)
 		student, err := qtx.CreateStudent(ctx, database.CreateStudentParams{
 			Name:        strings.ToLower(reqBody.Name),
 			Email:       reqBody.Email,
 			PhoneNumber: reqBody.PhoneNumber,
 			Nip:         reqBody.Nip,
-			Year:        int32(time.Now().Year()),
+			Year:        int32(year),
 			Nim:         nim,
 			DateOfBirth: studentBirthDate,
 			StudyPlanID: studentData.StudyPlan.ID,
-			RoomID:      studentData.Room.ID,
+			RoomID:      room.ID,
 		})
 		if err != nil {
 			return fmt.Errorf("here daddy 102, %v", err.Error())
@@ -140,7 +178,7 @@ func (config *apiConfig) HandlerCreateStudent(c echo.Context) error {
 		// add the student to the classroom
 		err = qtx.SetStudentClassroom(ctx, database.SetStudentClassroomParams{
 			StudentID: student.ID,
-			RoomID:    studentData.Room.ID,
+			RoomID:    room.ID,
 		})
 		if err != nil {
 			return fmt.Errorf("here daddy 111, %v", err.Error())
@@ -154,7 +192,12 @@ func (config *apiConfig) HandlerCreateStudent(c echo.Context) error {
 			return fmt.Errorf("here daddy 119, %v", err.Error())
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDENT_CREATE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			After:      student,
+		})
 	})
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Data{"error": err.Error()})
@@ -164,6 +207,15 @@ func (config *apiConfig) HandlerCreateStudent(c echo.Context) error {
 }
 
 func (config *apiConfig) HandlerDeleteStudent(c echo.Context) error {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, Data{"error": ERROR_MISSING_BEARER_TOKEN})
+	}
+
+	if allowed, _ := config.Server.Can(claims, "students", "delete"); !allowed {
+		return c.JSON(http.StatusForbidden, Data{"error": ERROR_FORBIDDEN_SCOPE})
+	}
+
 	ctx := c.Request().Context()
 	q := config.Server.Queries
 	var param struct {
@@ -195,8 +247,8 @@ func (config *apiConfig) HandlerDeleteStudent(c echo.Context) error {
 			return fmt.Errorf("here daddy 162, %v", err.Error())
 		}
 
-		// add their nim to the available nim
-		err = qtx.AddToFreelist(ctx, student.Nim)
+		// their nim goes back to the pool of the cohort
+		err = server.ReleaseNim(ctx, qtx, student.Nim, studyPlan.Major, int(student.Year))
 		if err != nil {
 			return fmt.Errorf("here daddy 168, %v", err.Error())
 		}
@@ -206,7 +258,12 @@ func (config *apiConfig) HandlerDeleteStudent(c echo.Context) error {
 			return fmt.Errorf("here daddy 173, %v", err.Error())
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDENT_DELETE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			Before:     student,
+		})
 	})
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Data{"error": err.Error()})
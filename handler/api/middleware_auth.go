@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+)
+
+var publicEndpoint = []string{
+	"/api/v1/health",
+	"/api/v1/auth/token",
+}
+
+// the first superuser is created before anyone can hold a token,
+// the handler decides when the missing claims are fine
+var bootstrapEndpoint = []string{
+	"/api/v1/admin/superuser/create",
+}
+
+// MiddlewareAuth reads "Authorization: Bearer <token>" & puts the same
+// server.Claims the web routes get into the context
+func (config *apiConfig) MiddlewareAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		reqPath := c.Path()
+
+		if slices.Contains(publicEndpoint, reqPath) {
+			return next(c)
+		}
+
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if header == "" && slices.Contains(bootstrapEndpoint, reqPath) {
+			return next(c)
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api"`)
+			return c.JSON(http.StatusUnauthorized, Data{"error": ERROR_MISSING_BEARER_TOKEN})
+		}
+
+		claims, err := config.Server.AuthenticateBearer(c.Request().Context(), strings.TrimSpace(token))
+		if err != nil {
+			if errors.Is(err, server.ErrInvalidAPIToken) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="api", error="invalid_token"`)
+				return c.JSON(http.StatusUnauthorized, Data{"error": err.Error()})
+			}
+			return c.JSON(http.StatusInternalServerError, Data{"error": err.Error()})
+		}
+
+		c.Set("claims", claims)
+
+		return next(c)
+	}
+}
@@ -1,9 +1,13 @@
 package web
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
@@ -20,6 +24,7 @@ func (config *webConfig) GetAdminLoginPage(c echo.Context) error {
 	return c.Render(http.StatusOK, "login-page", Data{
 		"Role":       utils.USER_ROLE_ADMIN,
 		"CSRF_Token": CSRFToken,
+		"SSO":        config.Server.OIDC != nil,
 	})
 }
 
@@ -48,6 +53,117 @@ func (config *webConfig) GetAdminPanelPage(c echo.Context) error {
 
 	return c.Render(http.StatusOK, "home", Data{
 		"CSRF_Token": CSRFToken,
-		"UserRole":   claims.Roles[0],
+		"UserRole":   claims.ActiveRole,
 	})
 }
+
+func (config *webConfig) GetJobsPage(c echo.Context) error {
+	context := c.Request().Context()
+	query := config.Server.Queries
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_getjobs:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code: ERR11500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "adminPanelPages", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	jobs, err := query.GetJobsLatest(context, 100)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR12500", err.Error()),
+		)
+	}
+
+	jobsCount, err := query.GetJobsCountByStatus(context)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR13500", err.Error()),
+		)
+	}
+
+	return c.Render(http.StatusOK, "db-jobs-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Jobs":       jobs,
+		"JobsCount":  jobsCount,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+var errJobNotDead = errors.New(utils.ERROR_JOB_NOT_DEAD)
+
+func (config *webConfig) RequeueJob(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	context := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code: ERR14500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "jobs", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	err = utils.WithTX(context, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		n, err := qtx.RequeueDeadJob(context, jobID)
+		if err != nil {
+			return err
+		}
+
+		// missing or not dead, nothing changed so nothing is logged
+		if n == 0 {
+			return errJobNotDead
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_JOB_REQUEUE,
+			TargetType: "job",
+			TargetID:   jobID.String(),
+			Before:     Data{"Status": "dead"},
+			After:      Data{"Status": "pending"},
+		})
+	})
+	if errors.Is(err, errJobNotDead) {
+		return c.Render(http.StatusNotFound, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/jobs")
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+var errAPIKeyNotFound = errors.New(utils.ERROR_API_KEY_NOT_FOUND)
+
+// the expiry choices of the form, 0 is the key without expiry
+var apiKeyExpiryDays = []int{30, 90, 365, 0}
+
+func (config *webConfig) GetAPIKeysPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_apikeys:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR121500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	keys, err := config.Server.Queries.GetAPIKeysByUserID(ctx, claims.UserID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR122500", err.Error()),
+		)
+	}
+
+	scopes, err := config.Server.ScopesFor(ctx, claims.Roles)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR142500", err.Error()),
+		)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "account-api-keys", Data{
+		"CSRF_Token": CSRFToken,
+		"UserID":     claims.UserID,
+		"UserRole":   claims.ActiveRole,
+		"Keys":       keys,
+		"Scopes":     scopes,
+		"ExpiryDays": apiKeyExpiryDays,
+		"Now":        time.Now(),
+	})
+}
+
+func (config *webConfig) CreateAPIKey(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR123500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		Name       string   `validate:"required,max=64,nochars,cheeky_sql_inject"`
+		Scopes     []string `validate:"required,min=1,dive,max=64"`
+		ExpireDays string   `validate:"required,numeric"`
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	params := &formParams{
+		Name:       c.FormValue("name"),
+		Scopes:     form["scopes"],
+		ExpireDays: c.FormValue("expire_days"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	var expireAt sql.NullTime
+	days, _ := strconv.Atoi(params.ExpireDays)
+	if days > 0 {
+		expireAt = sql.NullTime{Time: time.Now().AddDate(0, 0, days), Valid: true}
+	}
+
+	var key string
+	var apiKey database.ApiKey
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		var err error
+		key, apiKey, err = config.Server.IssueAPIKey(ctx, qtx, claims.UserID, params.Name, params.Scopes, expireAt)
+		if err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_API_KEY_CREATE,
+			TargetType: "apiKey",
+			TargetID:   apiKey.ID.String(),
+			After:      apiKey,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, server.ErrInvalidScope) {
+			return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+				"Message": err.Error(),
+			})
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR124500", err.Error()),
+		)
+	}
+
+	return c.Render(http.StatusOK, "api-key-created", Data{
+		"Key":    key,
+		"APIKey": apiKey,
+	})
+}
+
+func (config *webConfig) RevokeAPIKey(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR125500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	// the bearer tokens of the key stop working with it, they're looked
+	// up through the key
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		n, err := qtx.RevokeAPIKey(ctx, database.RevokeAPIKeyParams{
+			ID:     id,
+			UserID: claims.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errAPIKeyNotFound
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_API_KEY_REVOKE,
+			TargetType: "apiKey",
+			TargetID:   id.String(),
+			Before:     Data{"Revoked": false},
+			After:      Data{"Revoked": true},
+		})
+	})
+	if errors.Is(err, errAPIKeyNotFound) {
+		return c.Render(http.StatusNotFound, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR126500", err.Error()),
+		)
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/account/api-keys")
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// NOTE: admin level utilsFunc
+
+// GetAuditPage lists the latest audit events, filtered by the action, the
+// actor's email, the target (type or id) and the day range
+func (config *webConfig) GetAuditPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_audit:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR153500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "audit", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	var queryParams struct {
+		Action string `query:"action" validate:"omitempty,max=64,cheeky_sql_inject"`
+		Actor  string `query:"actor" validate:"omitempty,max=255,cheeky_sql_inject"`
+		Target string `query:"target" validate:"omitempty,max=128,cheeky_sql_inject"`
+		From   string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+		To     string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	}
+
+	if err := c.Bind(&queryParams); err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := c.Validate(&queryParams); err != nil {
+		return c.String(http.StatusUnprocessableEntity, utils.ERROR_INVALID_INPUT_DATA)
+	}
+
+	// the day range is inclusive, "to" runs until the end of the day
+	since := time.Time{}
+	until := time.Now().AddDate(0, 0, 1)
+	if queryParams.From != "" {
+		since, _ = time.Parse(time.DateOnly, queryParams.From)
+	}
+	if queryParams.To != "" {
+		to, _ := time.Parse(time.DateOnly, queryParams.To)
+		until = to.AddDate(0, 0, 1)
+	}
+
+	events, err := config.Server.Queries.SearchAuditEvents(ctx, database.SearchAuditEventsParams{
+		Action:     "%" + queryParams.Action + "%",
+		ActorEmail: "%" + strings.ToLower(queryParams.Actor) + "%",
+		Target:     "%" + queryParams.Target + "%",
+		Since:      since,
+		Until:      until,
+		RowLimit:   utils.AUDIT_PAGE_SIZE,
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR154500", err.Error()),
+		)
+	}
+
+	actions, err := config.Server.Queries.GetAuditEventActions(ctx)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR155500", err.Error()),
+		)
+	}
+
+	return c.Render(http.StatusOK, "db-audit-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Events":     events,
+		"Actions":    actions,
+		"Filter":     queryParams,
+		"PageSize":   utils.AUDIT_PAGE_SIZE,
+		"UserRole":   claims.ActiveRole,
+	})
+}
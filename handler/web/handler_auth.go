@@ -1,15 +1,19 @@
 package web
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"slices"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/sessionstore"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
@@ -43,6 +47,22 @@ func (config *webConfig) GetHomePage(c echo.Context) error {
 	})
 }
 
+// otherLoginURL is the login page the single role account belongs to when
+// it came in from the other one. The admin signs in at /admin/login, the
+// students & the teachers at /login
+func otherLoginURL(roles []string, role string) (string, bool) {
+	if len(roles) != 1 {
+		return "", false
+	}
+
+	isAdmin := roles[0] == utils.USER_ROLE_ADMIN
+	if isAdmin == (role == utils.USER_ROLE_ADMIN) {
+		return "", false
+	}
+
+	return loginURL(roles[0]), true
+}
+
 func (config *webConfig) GetLoginPage(c echo.Context) error {
 	CSRFToken, ok := c.Get("csrf").(string)
 	if !ok {
@@ -55,6 +75,7 @@ func (config *webConfig) GetLoginPage(c echo.Context) error {
 	return c.Render(http.StatusOK, "login-page", Data{
 		"Role":       utils.USER_ROLE_STUDENT,
 		"CSRF_Token": CSRFToken,
+		"SSO":        config.Server.OIDC != nil,
 	})
 }
 
@@ -92,6 +113,13 @@ func (config *webConfig) Login(redirectURL string, role string) echo.HandlerFunc
 
 		user, err := query.GetUserByEmail(ctx, params.Email)
 		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Render(http.StatusUnauthorized, "error-message", Data{
+					"Message":    utils.ERROR_FAILED_AUTHENTICATION,
+					"Role":       role,
+					"CSRF_Token": CSRFToken,
+				})
+			}
 			log.Println(err)
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
@@ -102,21 +130,26 @@ func (config *webConfig) Login(redirectURL string, role string) echo.HandlerFunc
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
 
-		switch len(userRoles) {
-		case 1:
-			if !slices.Contains(userRoles, role) && userRoles[0] == utils.USER_ROLE_ADMIN {
-				c.Response().Header().Set("HX-Redirect", "/admin/login")
-				return c.NoContent(http.StatusOK)
-			}
+		if otherLogin, ok := otherLoginURL(userRoles, role); ok {
+			c.Response().Header().Set("HX-Redirect", otherLogin)
+			return c.NoContent(http.StatusOK)
+		}
 
-			if !slices.Contains(userRoles, role) && userRoles[0] != utils.USER_ROLE_ADMIN {
-				c.Response().Header().Set("HX-Redirect", "/login")
-				return c.NoContent(http.StatusOK)
-			}
+		// the lockout is per account, checked before the password so the
+		// guessing stops even when the attacker rotates the ip
+		lockedUntil, locked, err := config.Server.LoginLockedUntil(ctx, user.ID)
+		if err != nil {
+			log.Println(err)
+			return c.String(http.StatusInternalServerError, err.Error())
 		}
 
-		isUserValid := utils.CheckPasswordHash(params.Password, user.PasswordHash)
-		if !isUserValid {
+		// the same answer as the unknown email, a lockout message would tell
+		// the account exists. The reason is only in the log
+		if locked {
+			log.Printf(
+				"LOGIN LOCKOUT: login of the locked user %v refused, locked until %v, from %s",
+				user.ID, lockedUntil.Format(time.DateTime), c.RealIP(),
+			)
 			return c.Render(http.StatusUnauthorized, "error-message", Data{
 				"Message":    utils.ERROR_FAILED_AUTHENTICATION,
 				"Role":       role,
@@ -124,34 +157,66 @@ func (config *webConfig) Login(redirectURL string, role string) echo.HandlerFunc
 			})
 		}
 
-		// session creation
-		sessionID := fmt.Sprintf("sess_id_%v_%v", user.ID, time.Now().Unix())
-		session, err := config.store.Get(c.Request(), config.sessionName)
+		isUserValid, rehash, err := config.Server.Passwords.Verify(params.Password, user.PasswordHash)
 		if err != nil {
 			log.Println(err)
-			return c.String(
-				http.StatusInternalServerError,
-				fmt.Sprintf("Internal Server Error, %v", err.Error()),
-			)
 		}
 
-		session.Values["session_id"] = sessionID
-		session.Values["user_id"] = fmt.Sprintf("%v", user.ID)
+		if !isUserValid {
+			delay, err := config.Server.RecordLoginFailure(ctx, user.ID, c.RealIP())
+			if err != nil {
+				log.Println(err)
+			}
+			time.Sleep(delay)
+
+			return c.Render(http.StatusUnauthorized, "error-message", Data{
+				"Message":    utils.ERROR_FAILED_AUTHENTICATION,
+				"Role":       role,
+				"CSRF_Token": CSRFToken,
+			})
+		}
+
+		// the hash of the older algorithm/parameters is replaced while the
+		// password is at hand, the login goes on even if it fails
+		if rehash {
+			config.rehashPassword(ctx, user.ID, params.Password, user.PasswordHash)
+		}
+
+		// checked after the password, the answer tells the account exists
+		if !user.EmailVerifiedAt.Valid {
+			return c.Render(http.StatusForbidden, "email-unverified", Data{
+				"Message":    utils.ERROR_EMAIL_NOT_VERIFIED,
+				"Email":      user.Email,
+				"Role":       role,
+				"CSRF_Token": CSRFToken,
+			})
+		}
 
-		_, err = query.CreateUserSession(ctx, database.CreateUserSessionParams{
-			SessionID: sessionID,
-			UserID:    user.ID,
-			ExpireAt:  time.Now().Add(24 * time.Hour),
-		})
+		// the superuser holds more than one role, the login page decides
+		// which one the session starts as
+		activeRole := server.DefaultActiveRole(userRoles, role)
+
+		// second factor, the session is only written after the code
+		required, err := config.isTwoFactorRequired(ctx, user, userRoles)
 		if err != nil {
 			log.Println(err)
-			return c.String(
-				http.StatusInternalServerError,
-				fmt.Sprintf("Internal Server Error, %v", err.Error()),
-			)
+			return c.String(http.StatusInternalServerError, err.Error())
 		}
 
-		if err := session.Save(c.Request(), c.Response()); err != nil {
+		if required {
+			if err := config.beginTwoFactor(c, user.ID, redirectURL, activeRole); err != nil {
+				log.Println(err)
+				return c.String(
+					http.StatusInternalServerError,
+					fmt.Sprintf("Internal Server Error, %v", err.Error()),
+				)
+			}
+
+			c.Response().Header().Set("HX-Redirect", twoFactorURL(role))
+			return c.NoContent(http.StatusOK)
+		}
+
+		if err := config.startUserSession(c, user.ID, activeRole); err != nil {
 			log.Println(err)
 			return c.String(
 				http.StatusInternalServerError,
@@ -168,7 +233,6 @@ func (config *webConfig) Logout(redirectURL string) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		time.Sleep(200 * time.Millisecond)
 		ctx := c.Request().Context()
-		query := config.Server.Queries
 
 		session, err := config.store.Get(c.Request(), config.sessionName)
 		if err != nil {
@@ -180,7 +244,7 @@ func (config *webConfig) Logout(redirectURL string) echo.HandlerFunc {
 
 		sessionID, ok := session.Values["session_id"].(string)
 		if ok && sessionID != "" {
-			if err := query.DeleteUserSession(ctx, sessionID); err != nil {
+			if err := config.Server.Sessions.Delete(ctx, sessionID); err != nil {
 				return c.String(
 					http.StatusInternalServerError,
 					fmt.Sprintf("Internal Server Error, %x", err),
@@ -202,3 +266,68 @@ func (config *webConfig) Logout(redirectURL string) echo.HandlerFunc {
 		return c.NoContent(http.StatusOK)
 	}
 }
+
+// rehashPassword only replaces the hash that was verified, a password
+// changed by another request in the meantime is kept
+func (config *webConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password, verifiedHash string) {
+	hashedPassword, err := config.Server.Passwords.Hash(password)
+	if err != nil {
+		log.Println("PASSWORD REHASH FAILED:", err)
+		return
+	}
+
+	if err := config.Server.Queries.UpdateUserPasswordHash(ctx, database.UpdateUserPasswordHashParams{
+		PasswordHash: hashedPassword,
+		ID:           userID,
+		VerifiedHash: verifiedHash,
+	}); err != nil {
+		log.Println("PASSWORD REHASH FAILED:", err)
+	}
+}
+
+// startUserSession writes the server side session & the cookie, the caller
+// has already checked every login factor. The failed attempts are forgotten
+// here, not after the password, so the second factor can't be guessed by
+// re-entering the password between the codes
+func (config *webConfig) startUserSession(c echo.Context, userID uuid.UUID, activeRole string) error {
+	ctx := c.Request().Context()
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return err
+	}
+
+	if err := config.Server.ClearLoginFailures(ctx, userID); err != nil {
+		return err
+	}
+
+	user, err := config.Server.Queries.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// the cookie gets the random token, the store only knows its hash
+	token, _, err := config.Server.Sessions.Create(ctx, sessionstore.CreateParams{
+		UserID:    userID,
+		TTL:       utils.SESSION_TTL,
+		UserAgent: truncate(c.Request().UserAgent(), utils.SESSION_USER_AGENT_MAX),
+		IPAddress: c.RealIP(),
+	})
+	if err != nil {
+		return err
+	}
+
+	clearTwoFactor(session)
+	clearImpersonation(session)
+	session.Values["session_id"] = token
+	session.Values[activeRoleKey] = activeRole
+
+	// MiddlewareAuthN holds the session on the change page until it's done
+	if user.PasswordChangeRequired {
+		session.Values[passwordChangeKey] = true
+	} else {
+		delete(session.Values, passwordChangeKey)
+	}
+
+	return session.Save(c.Request(), c.Response())
+}
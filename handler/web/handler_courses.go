@@ -0,0 +1,443 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/queue"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/storage"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+func (config *webConfig) GetCoursePage(c echo.Context) error {
+	context := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(http.StatusInternalServerError, "Please Contact Support with code:76500")
+	}
+
+	if allowed, _ := config.Server.Can(claims, "coursePage", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR77500", ""),
+		)
+	}
+
+	courses, err := query.GetCoursesByTeacherID(context, claims.UserID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR181500", err.Error()),
+		)
+	}
+
+	return c.Render(http.StatusOK, "course-page", Data{
+		"CSRF_Token": CSRFToken,
+		"UserID":     claims.UserID,
+		"UserRole":   utils.USER_ROLE_TEACHER,
+		"Courses":    courses,
+	})
+}
+
+func (config *webConfig) CreateCourse(c echo.Context) error {
+	context := c.Request().Context()
+	query := config.Server.Queries
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR74500", ""),
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR44500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "courses", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	type formParams struct {
+		Title     string `validate:"required,max=128,cheeky_sql_inject"`
+		Desc      string `validate:"max=2048,cheeky_sql_inject"`
+		CreatedAt string `validate:"required,datetime=2006-01-02"`
+	}
+
+	params := &formParams{
+		Title:     c.FormValue("course_title"),
+		Desc:      c.FormValue("course_desc"),
+		CreatedAt: c.FormValue("course_date"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	courseDate, err := time.Parse(time.DateOnly, params.CreatedAt)
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	// Generate ID to identified the file from storage
+	// Store it to the DB with corresponds course entry
+	courseFileID := uuid.New().String()
+	stagingKey := queue.CourseStagingKey(courseFileID)
+
+	file, err := c.FormFile("course")
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR88500", err.Error()),
+		)
+	}
+
+	defer c.Request().MultipartForm.RemoveAll()
+
+	src, err := file.Open()
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR87500", err.Error()),
+		)
+	}
+
+	defer src.Close()
+
+	// COPIES TO STAGING STORAGE (temp)
+	if err = config.Server.Storage.Put(
+		context,
+		stagingKey,
+		src,
+		file.Size,
+		file.Header.Get("Content-Type"),
+	); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR99500", err.Error()),
+		)
+	}
+
+	if err = utils.WithTX(context, config.Server.DB, query, func(qtx *database.Queries) error {
+		course, err := qtx.CreateCourse(context, database.CreateCourseParams{
+			Title:       params.Title,
+			Description: params.Desc,
+			CourseDate:  courseDate,
+			FileID:      courseFileID,
+			FileName:    filepath.Base(file.Filename),
+			TeacherID:   claims.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the worker validates & moves the file to permanent storage,
+		// enqueued in the same tx, so there is no job for a course that never exists
+		_, err = queue.Enqueue(context, qtx, queue.JOB_KIND_COURSE_UPLOAD, queue.CourseUploadPayload{
+			CourseID: course.ID,
+			FileID:   courseFileID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_COURSE_CREATE,
+			TargetType: "course",
+			TargetID:   course.ID.String(),
+			After:      course,
+		})
+	}); err != nil {
+		config.Server.Storage.Delete(context, stagingKey)
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"CSRF_Token": CSRFToken,
+			"Message":    err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/courses")
+	return c.NoContent(http.StatusCreated)
+}
+
+// getAuthorizedCourse loads the course from ":id" param, and checks the
+// courses policy of the action against it (courses:update:own is the
+// requesting teacher's own courses)
+func (config *webConfig) getAuthorizedCourse(c echo.Context, claims *server.Claims, action string) (database.Course, error) {
+	ctx := c.Request().Context()
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return database.Course{}, err
+	}
+
+	course, err := config.Server.Queries.GetCourseById(ctx, courseID)
+	if err != nil {
+		return database.Course{}, err
+	}
+
+	allowed, err := config.Server.Authorize(ctx, claims, "courses", action, server.Target{
+		OwnerID: course.TeacherID,
+	})
+	if err != nil {
+		return database.Course{}, err
+	}
+
+	if !allowed {
+		return database.Course{}, errors.New(utils.ERROR_USER_UNAUTHORIZED)
+	}
+
+	return course, nil
+}
+
+func (config *webConfig) GetUpdateCoursePage(c echo.Context) error {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR45500", ""),
+		)
+	}
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR75500", ""),
+		)
+	}
+
+	course, err := config.getAuthorizedCourse(c, claims, "update")
+	if err != nil {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	return c.Render(http.StatusOK, "course-update", Data{
+		"CSRF_Token": CSRFToken,
+		"UserID":     claims.UserID,
+		"UserRole":   utils.USER_ROLE_TEACHER,
+		"Course":     course,
+	})
+}
+
+func (config *webConfig) UpdateCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	context := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR46500", ""),
+		)
+	}
+
+	course, err := config.getAuthorizedCourse(c, claims, "update")
+	if err != nil {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	type formParams struct {
+		Title      string `validate:"required,max=128,cheeky_sql_inject"`
+		Desc       string `validate:"max=2048,cheeky_sql_inject"`
+		CourseDate string `validate:"required,datetime=2006-01-02"`
+	}
+
+	err = utils.WithTX(context, config.Server.DB, query, func(qtx *database.Queries) error {
+		params := formParams{
+			Title:      c.FormValue("course_title"),
+			Desc:       c.FormValue("course_desc"),
+			CourseDate: c.FormValue("course_date"),
+		}
+
+		if err := c.Validate(&params); err != nil {
+			return errors.New(utils.ERROR_INVALID_INPUT_DATA)
+		}
+
+		courseDate, err := time.Parse(time.DateOnly, params.CourseDate)
+		if err != nil {
+			return errors.New(utils.ERROR_INVALID_INPUT_DATA)
+		}
+
+		updated, err := qtx.UpdateCourse(context, database.UpdateCourseParams{
+			ID:          course.ID,
+			Title:       params.Title,
+			Description: params.Desc,
+			CourseDate:  courseDate,
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_COURSE_UPDATE,
+			TargetType: "course",
+			TargetID:   course.ID.String(),
+			Before:     course,
+			After:      updated,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/courses")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) ArchiveCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	context := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR47500", ""),
+		)
+	}
+
+	course, err := config.getAuthorizedCourse(c, claims, "archive")
+	if err != nil {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	err = utils.WithTX(context, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.ArchiveCourse(context, course.ID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_COURSE_ARCHIVE,
+			TargetType: "course",
+			TargetID:   course.ID.String(),
+			Before:     course,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/courses")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) DownloadCourse(c echo.Context) error {
+	context := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR48500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "courses", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusNotFound, "Course Not Found")
+	}
+
+	course, err := config.Server.Queries.GetCourseById(context, courseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Course Not Found")
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR49500", err.Error()),
+		)
+	}
+
+	// courses:download:own for the teacher of the course,
+	// courses:download:enrolled for the students enrolled to it
+	allowed, err := config.Server.Authorize(context, claims, "courses", "download", server.Target{
+		OwnerID:  course.TeacherID,
+		CourseID: course.ID,
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR67500", err.Error()),
+		)
+	}
+
+	if !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	// the file is only served after the worker validated & moved it
+	if course.FileStatus != queue.COURSE_FILE_READY {
+		return c.String(http.StatusNotFound, "Course File Not Ready")
+	}
+
+	obj, info, err := config.Server.Storage.Get(context, queue.CourseFileKey(course.FileID))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.String(http.StatusNotFound, "Course File Not Found")
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR50500", err.Error()),
+		)
+	}
+
+	defer obj.Close()
+
+	// validation based caching
+	lastModified := info.LastModified.UTC().Truncate(time.Second)
+
+	valid, ETag := IsCacheValid(c, lastModified)
+	if valid {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(course.FileName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := c.Response().Header()
+	header.Set("ETag", ETag)
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("Content-Type", contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": course.FileName,
+	}))
+
+	// ServeContent takes care of Range, If-Range & Last-Modified
+	http.ServeContent(c.Response(), c.Request(), course.FileName, lastModified, obj)
+	return nil
+}
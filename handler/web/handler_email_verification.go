@@ -0,0 +1,151 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+var errInvalidVerifyToken = errors.New(utils.ERROR_INVALID_VERIFY_TOKEN)
+
+// mailVerification sends the link of the account just committed. A failed
+// mail doesn't undo the account, the login offers to send a new link
+func (config *webConfig) mailVerification(c echo.Context, email, token string) {
+	err := utils.SendEmailVerification(c.Request().Context(), config.Server.Mailer, utils.AppURL(c), email, token)
+	if err != nil {
+		log.Println("EMAIL VERIFICATION MAIL FAILED:", err)
+	}
+}
+
+// GetVerifyEmailPage is the landing page of the mailed link, the link is
+// only a proof of the inbox so opening it is enough
+func (config *webConfig) GetVerifyEmailPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var userID uuid.UUID
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		var err error
+		// used_at is set in the same statement, the second open finds nothing
+		userID, err = qtx.ConsumeEmailVerification(ctx, tokens.Hash(c.QueryParam("token")))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidVerifyToken
+			}
+			return err
+		}
+
+		if err := qtx.MarkUserEmailVerified(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := qtx.InvalidateEmailVerificationsByUserID(ctx, userID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_EMAIL_VERIFY,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Before:     Data{"EmailVerified": false},
+			After:      Data{"EmailVerified": true},
+		})
+	})
+	if err != nil && !errors.Is(err, errInvalidVerifyToken) {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR175500", err.Error()),
+		)
+	}
+
+	loginURL := "/login"
+	if err == nil {
+		if roles, err := config.Server.LoadUserRoles(ctx, userID); err == nil && slices.Contains(roles, utils.USER_ROLE_ADMIN) {
+			loginURL = "/admin/login"
+		}
+		log.Printf("EMAIL VERIFIED: user %v", userID)
+	}
+
+	// the link is single use, don't let the browser keep the page
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Referrer-Policy", "no-referrer")
+
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "verify-email-page", Data{
+			"Invalid":  true,
+			"Message":  err.Error(),
+			"LoginURL": loginURL,
+		})
+	}
+
+	return c.Render(http.StatusOK, "verify-email-page", Data{
+		"Message":  utils.INFO_EMAIL_VERIFIED,
+		"LoginURL": loginURL,
+	})
+}
+
+// ResendEmailVerification is offered by the login of an unverified
+// account. It always answers the same message, so it can't be used to
+// find out which email has an account
+func (config *webConfig) ResendEmailVerification(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	type formParams struct {
+		Email string `validate:"email_constraints,cheeky_sql_inject"`
+	}
+
+	params := &formParams{
+		Email: c.FormValue("email"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ValidationErrorMsg(err.Error()),
+		})
+	}
+
+	sent := Data{"Message": utils.INFO_VERIFICATION_SENT}
+
+	user, err := query.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Render(http.StatusOK, "reset-message", sent)
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR176500", err.Error()),
+		)
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return c.Render(http.StatusOK, "reset-message", sent)
+	}
+
+	var token string
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		token, err = utils.NewEmailVerification(ctx, qtx, user.ID)
+		return err
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR177500", err.Error()),
+		)
+	}
+
+	// the same answer when the mail fails, an error would tell the
+	// account exists
+	config.mailVerification(c, user.Email, token)
+
+	return c.Render(http.StatusOK, "reset-message", sent)
+}
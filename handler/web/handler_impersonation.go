@@ -0,0 +1,285 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// the admin viewing the app as a student keeps the own session token,
+// the cookie session only adds the student & the end of the view.
+// MiddlewareAuthN swaps the user of the request while it lasts
+const (
+	impersonatedUserKey     = "impersonated_user_id"
+	impersonationExpireKey  = "impersonation_expire_at"
+	impersonationStopURL    = "/impersonation/stop"
+	impersonationReturnURL  = "/admin/panel/students"
+	impersonationTargetRole = utils.USER_ROLE_STUDENT
+)
+
+// the only changes allowed while viewing as a student, every other
+// request but GET & HEAD is refused
+var impersonationEndpoint = []string{
+	impersonationStopURL,
+	"/logout",
+	"/admin/logout",
+}
+
+func clearImpersonation(session *sessions.Session) {
+	delete(session.Values, impersonatedUserKey)
+	delete(session.Values, impersonationExpireKey)
+}
+
+// serveImpersonated runs the request as the student, the admin stays
+// the actor of the audit log. Every request is logged, the refused too
+func (config *webConfig) serveImpersonated(c echo.Context, session *sessions.Session, adminID uuid.UUID, adminRole string, next echo.HandlerFunc) error {
+	userIDStr, _ := session.Values[impersonatedUserKey].(string)
+	expireAt, _ := session.Values[impersonationExpireKey].(int64)
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil || time.Now().Unix() > expireAt {
+		clearImpersonation(session)
+		if err := session.Save(c.Request(), c.Response()); err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+
+		log.Printf("IMPERSONATION EXPIRED: admin %v as user %v", adminID, userIDStr)
+		return c.Redirect(http.StatusFound, impersonationReturnURL)
+	}
+
+	c.Set("impersonator_id", adminID)
+	c.Set("impersonator_role", adminRole)
+	c.Set("user_id", userID)
+	c.Set("active_role", impersonationTargetRole)
+
+	method := c.Request().Method
+	readOnly := method == http.MethodGet || method == http.MethodHead
+	if !readOnly && !slices.Contains(impersonationEndpoint, c.Path()) {
+		err = c.Render(http.StatusForbidden, "unauthorized", Data{
+			"Message": utils.ERROR_IMPERSONATION_READ_ONLY,
+		})
+	} else {
+		err = next(c)
+	}
+
+	status := c.Response().Status
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+	} else if err != nil {
+		status = http.StatusInternalServerError
+	}
+
+	// logged apart from the request, a failed log doesn't fail the page
+	if err := utils.Audit(c, config.Server.Queries, utils.AuditEvent{
+		Action:     utils.AUDIT_IMPERSONATION_REQUEST,
+		TargetType: "user",
+		TargetID:   userID.String(),
+		After: Data{
+			"Method": method,
+			"Path":   c.Request().URL.RequestURI(),
+			"Status": status,
+		},
+	}); err != nil {
+		log.Println("IMPERSONATION AUDIT FAILED:", err)
+	}
+
+	return err
+}
+
+// StartImpersonation is the "view as" of the admin students table, :id
+// is the user id of the student
+func (config *webConfig) StartImpersonation(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR163500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "users", "impersonate"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	roles, err := config.Server.LoadUserRoles(ctx, userID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR164500", err.Error()),
+		)
+	}
+
+	if userID == claims.UserID ||
+		!slices.Contains(roles, impersonationTargetRole) ||
+		slices.Contains(roles, utils.USER_ROLE_ADMIN) {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_IMPERSONATION_TARGET,
+		})
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR165500", err.Error()),
+		)
+	}
+
+	expireAt := time.Now().Add(utils.IMPERSONATION_TTL)
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_IMPERSONATION_START,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			After:      Data{"ExpireAt": expireAt.Format(time.RFC3339)},
+		})
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR166500", err.Error()),
+		)
+	}
+
+	// the role the admin acted as, back in place after the view
+	session.Values[activeRoleKey] = claims.ActiveRole
+	session.Values[impersonatedUserKey] = userID.String()
+	session.Values[impersonationExpireKey] = expireAt.Unix()
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR167500", err.Error()),
+		)
+	}
+
+	log.Printf("IMPERSONATION START: admin %v as user %v until %v", claims.UserID, userID, expireAt.Format(time.RFC3339))
+
+	c.Response().Header().Set("HX-Redirect", homeURL(impersonationTargetRole))
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) StopImpersonation(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR168500", ""),
+		)
+	}
+
+	if claims.Impersonator == uuid.Nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_NOT_IMPERSONATING,
+		})
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR169500", err.Error()),
+		)
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_IMPERSONATION_STOP,
+			TargetType: "user",
+			TargetID:   claims.UserID.String(),
+		})
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR170500", err.Error()),
+		)
+	}
+
+	clearImpersonation(session)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR171500", err.Error()),
+		)
+	}
+
+	log.Printf("IMPERSONATION STOP: admin %v as user %v", claims.Impersonator, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", impersonationReturnURL)
+	return c.NoContent(http.StatusOK)
+}
+
+// GetImpersonationBanner is loaded into the top pane, it's empty outside
+// of the impersonation
+func (config *webConfig) GetImpersonationBanner(c echo.Context) error {
+	ctx := c.Request().Context()
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_impersonationbanner:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR172500", ""),
+		)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	if claims.Impersonator == uuid.Nil {
+		return c.NoContent(http.StatusOK)
+	}
+
+	user, err := config.Server.Queries.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR173500", err.Error()),
+		)
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR174500", err.Error()),
+		)
+	}
+	expireAt, _ := session.Values[impersonationExpireKey].(int64)
+
+	return c.Render(http.StatusOK, "impersonation-banner", Data{
+		"CSRF_Token": CSRFToken,
+		"Email":      user.Email,
+		"ExpireAt":   time.Unix(expireAt, 0),
+	})
+}
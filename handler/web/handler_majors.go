@@ -0,0 +1,391 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// NOTE: admin level utilsFunc
+
+func (config *webConfig) GetMajorsPage(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_getmajors:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR69500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "majors", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	majors, err := query.GetMajorAll(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	rooms, err := query.GetRoomAll(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Render(http.StatusOK, "db-majors-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Majors":     majors,
+		"Rooms":      rooms,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) CreateMajor(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR70500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "majors", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		Name string `validate:"required,max=64,name_constraints,cheeky_sql_inject"`
+		Code string `validate:"required,max=8,alphanum"`
+	}
+
+	params := &formParams{
+		Name: strings.ToUpper(strings.TrimSpace(c.FormValue("major_name"))),
+		Code: strings.ToUpper(strings.TrimSpace(c.FormValue("major_code"))),
+	}
+
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := c.Validate(params); err != nil {
+			return errors.New(utils.ERROR_INVALID_INPUT_DATA)
+		}
+
+		major, err := qtx.CreateMajor(ctx, database.CreateMajorParams{
+			Name: params.Name,
+			Code: params.Code,
+		})
+		if err != nil {
+			return err
+		}
+
+		// every major walks through the same semesters, the study plans
+		// have to be there for the enrollment & promotion
+		for semester := 1; semester <= utils.STUDY_PLAN_SEMESTERS; semester++ {
+			if err = qtx.CreateStudyPlan(ctx, database.CreateStudyPlanParams{
+				Semester: int32(semester),
+				Major:    major.Name,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_MAJOR_CREATE,
+			TargetType: "major",
+			TargetID:   major.ID.String(),
+			After:      major,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Catalog.Invalidate()
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/majors")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (config *webConfig) DeleteMajor(c echo.Context) error {
+	time.Sleep(300 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR71500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "majors", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		majorID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		major, err := qtx.GetMajorById(ctx, majorID)
+		if err != nil {
+			return err
+		}
+
+		n, err := qtx.CountStudentsByMajor(ctx, major.Name)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return errors.New(utils.ERROR_MAJOR_HAS_STUDENTS)
+		}
+
+		if err = qtx.DeleteRoomsByMajor(ctx, major.Name); err != nil {
+			return err
+		}
+
+		if err = qtx.DeleteStudyPlansByMajor(ctx, major.Name); err != nil {
+			return err
+		}
+
+		if err = qtx.DeleteMajorById(ctx, major.ID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_MAJOR_DELETE,
+			TargetType: "major",
+			TargetID:   major.ID.String(),
+			Before:     major,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Catalog.Invalidate()
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/majors")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) CreateRoom(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR72500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "rooms", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		Name     string `validate:"required,max=64,alphanum"`
+		Capacity string `validate:"required,number"`
+		Major    string `validate:"required,oneof_major"`
+	}
+
+	params := &formParams{
+		Name:     strings.ToUpper(strings.TrimSpace(c.FormValue("room_name"))),
+		Capacity: c.FormValue("room_capacity"),
+		Major:    c.FormValue("room_major"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	capacity, err := strconv.Atoi(params.Capacity)
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		room, err := qtx.CreateRoom(ctx, database.CreateRoomParams{
+			Name:     params.Name,
+			Capacity: int32(capacity),
+			Major:    params.Major,
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROOM_CREATE,
+			TargetType: "room",
+			TargetID:   room.ID.String(),
+			After:      room,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Catalog.Invalidate()
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/majors")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (config *webConfig) UpdateRoom(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR73500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "rooms", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	capacity, err := strconv.Atoi(c.FormValue("room_capacity"))
+	if err != nil || capacity < 0 {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		room, err := qtx.GetStudentRoomById(ctx, roomID)
+		if err != nil {
+			return err
+		}
+
+		if err = qtx.UpdateRoomCapacity(ctx, database.UpdateRoomCapacityParams{
+			ID:       roomID,
+			Capacity: int32(capacity),
+		}); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROOM_UPDATE,
+			TargetType: "room",
+			TargetID:   room.ID.String(),
+			Before:     Data{"Capacity": room.Capacity},
+			After:      Data{"Capacity": capacity},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/majors")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) DeleteRoom(c echo.Context) error {
+	time.Sleep(300 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR83500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "rooms", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		room, err := qtx.GetStudentRoomById(ctx, roomID)
+		if err != nil {
+			return err
+		}
+
+		// the students keep their room_id (fk), a room with students can't go
+		if err = qtx.DeleteRoomById(ctx, roomID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROOM_DELETE,
+			TargetType: "room",
+			TargetID:   room.ID.String(),
+			Before:     room,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Catalog.Invalidate()
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/majors")
+	return c.NoContent(http.StatusOK)
+}
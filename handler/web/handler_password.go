@@ -0,0 +1,250 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// passwordChangeKey marks the session of the user the admin asked for a
+// new password, the session can't go anywhere else than the change page
+const passwordChangeKey = "password_change_required"
+
+// the endpoints the marked session still reaches
+var passwordChangeEndpoint = []string{
+	"/account/password",
+	"/account/role",
+	"/logout",
+	"/admin/logout",
+}
+
+func (config *webConfig) GetChangePasswordPage(c echo.Context) error {
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_changepassword:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR156500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	required, _ := session.Values[passwordChangeKey].(bool)
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "account-password", Data{
+		"CSRF_Token": CSRFToken,
+		"UserID":     claims.UserID,
+		"UserRole":   claims.ActiveRole,
+		"Required":   required,
+		"Message":    utils.INFO_PASSWORD_CHANGE_REQUIRED,
+	})
+}
+
+// ChangePassword takes the current password again, the session alone is
+// not enough to lock the owner out. Every other session of the user ends
+func (config *webConfig) ChangePassword(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR157500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		CurrentPassword string `validate:"password_constraints"`
+		Password        string `validate:"password_constraints"`
+		ConfirmPassword string `validate:"password_constraints"`
+	}
+
+	params := &formParams{
+		CurrentPassword: c.FormValue("current-password"),
+		Password:        c.FormValue("password"),
+		ConfirmPassword: c.FormValue("confirm-password"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ValidationErrorMsg(err.Error()),
+		})
+	}
+
+	if params.Password != params.ConfirmPassword {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_CONFIRM_PASSWORD,
+		})
+	}
+
+	if params.Password == params.CurrentPassword {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_PASSWORD_UNCHANGED,
+		})
+	}
+
+	user, err := config.Server.Queries.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR158500", err.Error()),
+		)
+	}
+
+	if valid, _, _ := config.Server.Passwords.Verify(params.CurrentPassword, user.PasswordHash); !valid {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_WRONG_CURRENT_PASSWORD,
+		})
+	}
+
+	hashedPassword, err := config.Server.Passwords.Hash(params.Password)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR159500", err.Error()),
+		)
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hashedPassword,
+		}); err != nil {
+			return err
+		}
+
+		// a reset link asked before the change shouldn't undo it
+		if err := qtx.InvalidatePasswordResetsByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_PASSWORD_CHANGE,
+			TargetType: "user",
+			TargetID:   user.ID.String(),
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	if err := config.Server.Sessions.RevokeOthers(ctx, user.ID, config.currentSessionToken(c)); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR160500", err.Error()),
+		)
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	delete(session.Values, passwordChangeKey)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("HX-Redirect", homeURL(claims.ActiveRole))
+	return c.NoContent(http.StatusOK)
+}
+
+// NOTE: admin level utilsFunc
+
+// RequirePasswordChange logs the user out everywhere, the next login
+// lands on the change page until a new password is picked
+func (config *webConfig) RequirePasswordChange(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR161500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "users", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		n, err := qtx.RequireUserPasswordChange(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return errors.New(utils.ERROR_USER_NOT_FOUND)
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_PASSWORD_REQUIRE_CHANGE,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Before:     Data{"ChangeRequired": false},
+			After:      Data{"ChangeRequired": true},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	if err := config.Server.Sessions.RevokeAll(ctx, userID); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR162500", err.Error()),
+		)
+	}
+
+	log.Printf("PASSWORD CHANGE REQUIRED: user %v by admin %v", userID, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/users")
+	return c.NoContent(http.StatusOK)
+}
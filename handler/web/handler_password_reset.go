@@ -0,0 +1,259 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/mailer"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+func (config *webConfig) GetForgotPasswordPage(role string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		CSRFToken, ok := c.Get("csrf").(string)
+		if !ok {
+			return c.String(
+				http.StatusInternalServerError,
+				"Internal Server Error, at debug_block_forgotpassword:1",
+			)
+		}
+
+		return c.Render(http.StatusOK, "forgot-password-page", Data{
+			"Role":       role,
+			"CSRF_Token": CSRFToken,
+		})
+	}
+}
+
+// ForgotPassword always answers the same message, so the page can't be
+// used to find out which email has an account
+func (config *webConfig) ForgotPassword(role string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		ctx := c.Request().Context()
+		query := config.Server.Queries
+
+		type formParams struct {
+			Email string `validate:"email_constraints,cheeky_sql_inject"`
+		}
+
+		params := &formParams{
+			Email: c.FormValue("email"),
+		}
+
+		if err := c.Validate(params); err != nil {
+			return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+				"Message": utils.ValidationErrorMsg(err.Error()),
+			})
+		}
+
+		sent := Data{"Message": utils.INFO_PASSWORD_RESET_SENT}
+
+		user, err := query.GetUserByEmail(ctx, params.Email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Render(http.StatusOK, "reset-message", sent)
+			}
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR84500", err.Error()),
+			)
+		}
+
+		roles, err := config.Server.LoadUserRoles(ctx, user.ID)
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR85500", err.Error()),
+			)
+		}
+
+		// the admin resets from the admin login, everyone else from /login
+		if slices.Contains(roles, utils.USER_ROLE_ADMIN) != (role == utils.USER_ROLE_ADMIN) {
+			return c.Render(http.StatusOK, "reset-message", sent)
+		}
+
+		token, tokenHash, err := tokens.Generate()
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR86500", err.Error()),
+			)
+		}
+
+		err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+			// only the latest link works
+			if err := qtx.InvalidatePasswordResetsByUserID(ctx, user.ID); err != nil {
+				return err
+			}
+
+			_, err := qtx.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+				UserID:    user.ID,
+				TokenHash: tokenHash,
+				ExpireAt:  time.Now().Add(utils.PASSWORD_RESET_TOKEN_TTL),
+			})
+			return err
+		})
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR89500", err.Error()),
+			)
+		}
+
+		link := utils.AppURL(c) + "/reset-password?token=" + url.QueryEscape(token)
+
+		err = config.Server.Mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your RambanBelajar password",
+			Body: fmt.Sprintf(
+				"Someone asked to reset the password of your RambanBelajar account.\n\n"+
+					"Open the link below to choose a new password, it expires in %v:\n%s\n\n"+
+					"If it wasn't you, simply ignore this email.\n",
+				utils.PASSWORD_RESET_TOKEN_TTL, link,
+			),
+		})
+		// the same answer when the mail fails, an error would tell the
+		// account exists
+		if err != nil {
+			log.Println("PASSWORD RESET MAIL FAILED:", err)
+		}
+
+		return c.Render(http.StatusOK, "reset-message", sent)
+	}
+}
+
+func (config *webConfig) GetResetPasswordPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_resetpassword:1",
+		)
+	}
+
+	token := c.QueryParam("token")
+
+	_, err := config.Server.Queries.GetActivePasswordReset(ctx, tokens.Hash(token))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR91500", err.Error()),
+		)
+	}
+
+	// the link is single use, don't let the browser keep the page
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Referrer-Policy", "no-referrer")
+	return c.Render(http.StatusOK, "reset-password-page", Data{
+		"CSRF_Token": CSRFToken,
+		"Token":      token,
+		"Invalid":    err != nil,
+		"Message":    utils.ERROR_INVALID_RESET_TOKEN,
+	})
+}
+
+func (config *webConfig) ResetPassword(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	type formParams struct {
+		Token           string `validate:"required,max=64"`
+		Password        string `validate:"password_constraints"`
+		ConfirmPassword string `validate:"password_constraints"`
+	}
+
+	params := &formParams{
+		Token:           c.FormValue("token"),
+		Password:        c.FormValue("password"),
+		ConfirmPassword: c.FormValue("confirm-password"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	if params.Password != params.ConfirmPassword {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_CONFIRM_PASSWORD,
+		})
+	}
+
+	hashedPassword, err := config.Server.Passwords.Hash(params.Password)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR92500", err.Error()),
+		)
+	}
+
+	var userID uuid.UUID
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		// used_at is set in the same statement, the second submit finds nothing
+		userID, err = qtx.ConsumePasswordReset(ctx, tokens.Hash(params.Token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New(utils.ERROR_INVALID_RESET_TOKEN)
+			}
+			return err
+		}
+
+		if err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			ID:           userID,
+			PasswordHash: hashedPassword,
+		}); err != nil {
+			return err
+		}
+
+		if err := qtx.InvalidatePasswordResetsByUserID(ctx, userID); err != nil {
+			return err
+		}
+
+		// the link came through the inbox, the same proof as the verification
+		if err := qtx.MarkUserEmailVerified(ctx, userID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_PASSWORD_RESET,
+			TargetType: "user",
+			TargetID:   userID.String(),
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	// whoever was logged in with the old password is out
+	if err := config.Server.Sessions.RevokeAll(ctx, userID); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR179500", err.Error()),
+		)
+	}
+
+	redirectURL := "/login"
+	if roles, err := config.Server.LoadUserRoles(ctx, userID); err == nil && slices.Contains(roles, utils.USER_ROLE_ADMIN) {
+		redirectURL = "/admin/login"
+	}
+
+	c.Response().Header().Set("HX-Redirect", redirectURL)
+	return c.NoContent(http.StatusOK)
+}
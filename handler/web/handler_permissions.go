@@ -0,0 +1,375 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// the postgres code of the UNIQUE (role, permission) conflict
+const pqUniqueViolation = "23505"
+
+// roleView is a role of the permission editor with its permissions
+type roleView struct {
+	database.Role
+	Permissions []string
+}
+
+// NOTE: admin level utilsFunc
+
+func (config *webConfig) GetPermissionsPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_permissions:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR143500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "permissions", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	// the page reads the tables, not the cache, so it shows what is saved
+	roles, err := config.Server.Queries.GetRolesAll(ctx)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR144500", err.Error()),
+		)
+	}
+
+	rows, err := config.Server.Queries.GetRolePermissionsAll(ctx)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR145500", err.Error()),
+		)
+	}
+
+	views := make([]roleView, 0, len(roles))
+	for _, role := range roles {
+		view := roleView{Role: role}
+		for _, row := range rows {
+			if row.Role == role.Name {
+				view.Permissions = append(view.Permissions, row.Permission)
+			}
+		}
+		views = append(views, view)
+	}
+
+	return c.Render(http.StatusOK, "db-permissions-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Roles":      views,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) CreateRole(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR146500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "permissions", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		Name        string `validate:"required,max=64,lowercase,alpha"`
+		Description string `validate:"omitempty,max=255,nochars,cheeky_sql_inject"`
+	}
+
+	params := &formParams{
+		Name:        c.FormValue("name"),
+		Description: c.FormValue("description"),
+	}
+
+	if err := c.Validate(params); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if _, err := qtx.GetRoleByName(ctx, params.Name); err == nil {
+			return errors.New(utils.ERROR_ROLE_EXISTS)
+		}
+
+		role, err := qtx.CreateRole(ctx, database.CreateRoleParams{
+			Name:        params.Name,
+			Description: params.Description,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the role shows up on the security page with the other roles
+		if err = qtx.CreateRolePolicy(ctx, params.Name); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROLE_CREATE,
+			TargetType: "role",
+			TargetID:   role.Name,
+			After:      role,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Server.Permissions.Invalidate()
+	log.Printf("PERMISSION CHANGE: role %v created by admin %v", params.Name, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/permissions")
+	return c.NoContent(http.StatusCreated)
+}
+
+// DeleteRole only takes the roles nobody holds, the system roles stay
+func (config *webConfig) DeleteRole(c echo.Context) error {
+	time.Sleep(300 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR147500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "permissions", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	role := c.Param("role")
+
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		n, err := qtx.CountUsersByRole(ctx, role)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return errors.New(utils.ERROR_ROLE_IN_USE)
+		}
+
+		deleted, err := qtx.DeleteRole(ctx, role)
+		if err != nil {
+			return err
+		}
+
+		if deleted == 0 {
+			return errors.New(utils.ERROR_ROLE_NOT_DELETABLE)
+		}
+
+		if err = qtx.DeleteRolePolicy(ctx, role); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROLE_DELETE,
+			TargetType: "role",
+			TargetID:   role,
+			Before:     Data{"Name": role},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Server.Permissions.Invalidate()
+	log.Printf("PERMISSION CHANGE: role %v deleted by admin %v", role, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/permissions")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) AddRolePermission(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR148500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "permissions", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	role := c.Param("role")
+	permission := c.FormValue("permission")
+
+	if !server.ValidPermission(permission) {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_PERMISSION,
+		})
+	}
+
+	grantable, err := config.Server.CanGrant(ctx, claims, permission)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR187500", err.Error()),
+		)
+	}
+
+	if !grantable {
+		return c.Render(http.StatusForbidden, "error-message", Data{
+			"Message": utils.ERROR_PERMISSION_NOT_HELD,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if _, err := qtx.GetRoleByName(ctx, role); err != nil {
+			return errors.New(utils.ERROR_ROLE_NOT_FOUND)
+		}
+
+		if _, err := qtx.CreateRolePermission(ctx, database.CreateRolePermissionParams{
+			Role:       role,
+			Permission: permission,
+		}); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+				return errors.New(utils.ERROR_PERMISSION_EXISTS)
+			}
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROLE_PERMISSION_CREATE,
+			TargetType: "role",
+			TargetID:   role,
+			After:      Data{"Permission": permission},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Server.Permissions.Invalidate()
+	log.Printf("PERMISSION CHANGE: %v granted to role %v by admin %v", permission, role, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/permissions")
+	return c.NoContent(http.StatusCreated)
+}
+
+// RemoveRolePermission refuses to take away the editor's own access to
+// the editor, nobody could give it back without the database
+func (config *webConfig) RemoveRolePermission(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR149500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "permissions", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	role := c.Param("role")
+	permission := c.FormValue("permission")
+
+	if role == claims.ActiveRole {
+		permissions, err := config.Server.Permissions.Of(ctx, role)
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR150500", err.Error()),
+			)
+		}
+
+		remaining := slices.DeleteFunc(slices.Clone(permissions), func(p string) bool {
+			return p == permission
+		})
+
+		if !server.PermissionsAllow(remaining, "permissions", "update") {
+			return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+				"Message": utils.ERROR_PERMISSION_LOCKOUT,
+			})
+		}
+	}
+
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		n, err := qtx.DeleteRolePermission(ctx, database.DeleteRolePermissionParams{
+			Role:       role,
+			Permission: permission,
+		})
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return errors.New(utils.ERROR_PERMISSION_NOT_FOUND)
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ROLE_PERMISSION_DELETE,
+			TargetType: "role",
+			TargetID:   role,
+			Before:     Data{"Permission": permission},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	config.Server.Permissions.Invalidate()
+	log.Printf("PERMISSION CHANGE: %v revoked from role %v by admin %v", permission, role, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/permissions")
+	return c.NoContent(http.StatusOK)
+}
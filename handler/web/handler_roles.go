@@ -0,0 +1,91 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// the role the session acts as, kept in the cookie session next to the
+// session token. MiddlewareAuthZ checks it against the roles every request
+const activeRoleKey = "active_role"
+
+func homeURL(role string) string {
+	if role == utils.USER_ROLE_ADMIN {
+		return "/admin/panel"
+	}
+	return "/"
+}
+
+// GetRoleSwitcher is loaded into the nav, it's empty for the users
+// holding a single role
+func (config *webConfig) GetRoleSwitcher(c echo.Context) error {
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_roleswitcher:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR138500", ""),
+		)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "role-switcher", Data{
+		"CSRF_Token": CSRFToken,
+		"Roles":      claims.Roles,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) SwitchActiveRole(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR139500", ""),
+		)
+	}
+
+	// only the roles the user holds, the permissions follow the active role
+	role := c.FormValue("role")
+	if !slices.Contains(claims.Roles, role) {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR140500", err.Error()),
+		)
+	}
+
+	session.Values[activeRoleKey] = role
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR141500", err.Error()),
+		)
+	}
+
+	log.Printf("ROLE SWITCH: user %v from %v to %v", claims.UserID, claims.ActiveRole, role)
+
+	c.Response().Header().Set("HX-Redirect", homeURL(role))
+	return c.NoContent(http.StatusOK)
+}
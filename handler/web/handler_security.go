@@ -0,0 +1,108 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// NOTE: admin level utilsFunc, the security panel: the 2fa policies of
+// the roles (handler_two_factor.go) and the login lockouts
+
+func (config *webConfig) GetSecurityPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_getsecurity:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR105500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "security", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	policies, err := config.Server.Queries.GetRolePolicyAll(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	lockouts, err := config.Server.Queries.GetLoginLockoutAll(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Render(http.StatusOK, "db-security-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Policies":   policies,
+		"Lockouts":   lockouts,
+		"Now":        time.Now(),
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) UnlockLoginAccount(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR107500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "security", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.DeleteLoginLockout(ctx, userID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_LOGIN_UNLOCK,
+			TargetType: "user",
+			TargetID:   userID.String(),
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	log.Printf("LOGIN UNLOCK: user %v unlocked by admin %v", userID, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/security")
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,360 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/sessionstore"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+var errSessionNotFound = errors.New(utils.ERROR_SESSION_NOT_FOUND)
+
+// sessionView is what the sessions pages get, with the device spelled out
+type sessionView struct {
+	sessionstore.Session
+	Device  string
+	Current bool
+}
+
+func toSessionViews(sessions []sessionstore.Session, currentID uuid.UUID) []sessionView {
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{
+			Session: s,
+			Device:  describeUserAgent(s.UserAgent),
+			Current: s.ID == currentID,
+		})
+	}
+	return views
+}
+
+// currentSessionToken is the token of the cookie, it's the credential
+// so it only goes to the store, never to the template
+func (config *webConfig) currentSessionToken(c echo.Context) string {
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return ""
+	}
+
+	token, _ := session.Values["session_id"].(string)
+	return token
+}
+
+func (config *webConfig) currentSessionID(c echo.Context) uuid.UUID {
+	current, err := config.Server.Sessions.Get(c.Request().Context(), config.currentSessionToken(c))
+	if err != nil {
+		return uuid.Nil
+	}
+	return current.ID
+}
+
+// NOTE: account level utilsFunc
+
+func (config *webConfig) GetMySessionsPage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_mysessions:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR108500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	sessions, err := config.Server.Sessions.ListActive(ctx, claims.UserID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR109500", err.Error()),
+		)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "account-sessions", Data{
+		"CSRF_Token": CSRFToken,
+		"UserID":     claims.UserID,
+		"UserRole":   claims.ActiveRole,
+		"Sessions":   toSessionViews(sessions, config.currentSessionID(c)),
+	})
+}
+
+// RevokeMySession ends one session of the user, revoking the current one
+// is the same as the logout
+func (config *webConfig) RevokeMySession(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR110500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	// revoking by the user's own id keeps it to the user's own sessions
+	revoked, err := config.Server.Sessions.Revoke(ctx, claims.UserID, id)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR111500", err.Error()),
+		)
+	}
+
+	if !revoked {
+		return c.Render(http.StatusNotFound, "error-message", Data{
+			"Message": utils.ERROR_SESSION_NOT_FOUND,
+		})
+	}
+
+	redirectURL := "/account/sessions"
+	if config.currentSessionID(c) == id {
+		redirectURL = loginURL(claims.ActiveRole)
+	}
+
+	c.Response().Header().Set("HX-Redirect", redirectURL)
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) RevokeMyOtherSessions(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR112500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	err := config.Server.Sessions.RevokeOthers(ctx, claims.UserID, config.currentSessionToken(c))
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR113500", err.Error()),
+		)
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/account/sessions")
+	return c.NoContent(http.StatusOK)
+}
+
+// NOTE: admin level utilsFunc
+
+func (config *webConfig) GetUserSessionsPage(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_usersessions:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR114500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "sessions", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	user, err := query.GetUserById(ctx, userID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR115500", err.Error()),
+		)
+	}
+
+	sessions, err := config.Server.Sessions.ListActive(ctx, userID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR116500", err.Error()),
+		)
+	}
+
+	return c.Render(http.StatusOK, "db-user-sessions-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"UserRole":   claims.ActiveRole,
+		"User":       user,
+		"Sessions":   toSessionViews(sessions, config.currentSessionID(c)),
+	})
+}
+
+func (config *webConfig) RevokeUserSession(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR117500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "sessions", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	// the store isn't part of the tx, the revoke goes last so the event
+	// is rolled back when it fails
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_SESSION_REVOKE,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			After:      Data{"SessionID": sessionID},
+		}); err != nil {
+			return err
+		}
+
+		revoked, err := config.Server.Sessions.Revoke(ctx, userID, sessionID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return errSessionNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, errSessionNotFound) {
+		return c.Render(http.StatusNotFound, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR118500", err.Error()),
+		)
+	}
+
+	log.Printf("SESSION REVOKE: session %v of user %v revoked by admin %v", sessionID, userID, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/users/"+userID.String()+"/sessions")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) RevokeAllUserSessions(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR119500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "sessions", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_SESSION_REVOKE_ALL,
+			TargetType: "user",
+			TargetID:   userID.String(),
+		}); err != nil {
+			return err
+		}
+
+		return config.Server.Sessions.RevokeAll(ctx, userID)
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR120500", err.Error()),
+		)
+	}
+
+	log.Printf("SESSION REVOKE: every session of user %v revoked by admin %v", userID, claims.UserID)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/users/"+userID.String()+"/sessions")
+	return c.NoContent(http.StatusOK)
+}
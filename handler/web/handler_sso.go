@@ -0,0 +1,239 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/oidc"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+const (
+	// the web_session cookie is SameSite strict, the browser doesn't send
+	// it on the way back from the provider. The pending login gets its own
+	// lax cookie that only lives for the round trip
+	ssoSessionName = "web_sso"
+	ssoStateKey    = "sso_state"
+	ssoNonceKey    = "sso_nonce"
+	ssoVerifierKey = "sso_verifier"
+	ssoRoleKey     = "sso_role"
+	ssoPendingTTL  = 10 * time.Minute
+)
+
+func (config *webConfig) ssoSession(c echo.Context) (*sessions.Session, error) {
+	session, err := config.store.Get(c.Request(), ssoSessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Options = &sessions.Options{
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   config.store.Options.Secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(ssoPendingTTL.Seconds()),
+	}
+
+	return session, nil
+}
+
+// ssoLoginError shows the login page again with what went wrong, the
+// callback is a plain navigation, not htmx
+func (config *webConfig) ssoLoginError(c echo.Context, status int, role, message string) error {
+	CSRFToken, _ := c.Get("csrf").(string)
+
+	return c.Render(status, "login-page", Data{
+		"Role":       role,
+		"CSRF_Token": CSRFToken,
+		"SSO":        true,
+		"Error":      message,
+	})
+}
+
+// StartSSO sends the browser to the provider, with the state, nonce &
+// the PKCE verifier kept in the pending cookie
+func (config *webConfig) StartSSO(role string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		provider := config.Server.OIDC
+		if provider == nil {
+			return c.Redirect(http.StatusFound, loginURL(role))
+		}
+
+		authReq, err := oidc.NewAuthRequest()
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR127500", err.Error()),
+			)
+		}
+
+		authURL, err := provider.AuthURL(c.Request().Context(), authReq)
+		if err != nil {
+			log.Println(err)
+			return config.ssoLoginError(c, http.StatusBadGateway, role, utils.ERROR_SSO_FAILED)
+		}
+
+		session, err := config.ssoSession(c)
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR128500", err.Error()),
+			)
+		}
+
+		session.Values[ssoStateKey] = authReq.State
+		session.Values[ssoNonceKey] = authReq.Nonce
+		session.Values[ssoVerifierKey] = authReq.Verifier
+		session.Values[ssoRoleKey] = role
+
+		if err := session.Save(c.Request(), c.Response()); err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR129500", err.Error()),
+			)
+		}
+
+		return c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// SSOCallback is where the provider sends the browser back with the code.
+// The account is found by the identity or the verified email, the roles
+// follow the groups, then it's the same second factor & session as the
+// password login
+func (config *webConfig) SSOCallback(c echo.Context) error {
+	ctx := c.Request().Context()
+	provider := config.Server.OIDC
+	if provider == nil {
+		return c.Redirect(http.StatusFound, "/login")
+	}
+
+	session, err := config.ssoSession(c)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR130500", err.Error()),
+		)
+	}
+
+	state, _ := session.Values[ssoStateKey].(string)
+	role, _ := session.Values[ssoRoleKey].(string)
+	authReq := oidc.AuthRequest{State: state}
+	authReq.Nonce, _ = session.Values[ssoNonceKey].(string)
+	authReq.Verifier, _ = session.Values[ssoVerifierKey].(string)
+
+	if role != utils.USER_ROLE_ADMIN {
+		role = utils.USER_ROLE_STUDENT
+	}
+
+	// the pending login is single use, whatever comes next
+	session.Options.MaxAge = -1
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR131500", err.Error()),
+		)
+	}
+
+	if state == "" || c.QueryParam("state") != state {
+		return config.ssoLoginError(c, http.StatusBadRequest, role, utils.ERROR_SSO_EXPIRED)
+	}
+
+	if errParam := c.QueryParam("error"); errParam != "" {
+		log.Printf("SSO ERROR: the provider answered %v: %v", errParam, c.QueryParam("error_description"))
+		return config.ssoLoginError(c, http.StatusUnauthorized, role, utils.ERROR_SSO_FAILED)
+	}
+
+	code := c.QueryParam("code")
+	if code == "" {
+		return config.ssoLoginError(c, http.StatusBadRequest, role, utils.ERROR_SSO_FAILED)
+	}
+
+	claims, err := provider.Exchange(ctx, code, authReq)
+	if err != nil {
+		log.Printf("SSO ERROR: %v", err)
+		return config.ssoLoginError(c, http.StatusUnauthorized, role, utils.ERROR_SSO_FAILED)
+	}
+
+	userID, err := config.Server.LinkSSOUser(ctx, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, server.ErrSSOUnverifiedEmail):
+			return config.ssoLoginError(c, http.StatusUnauthorized, role, utils.ERROR_SSO_UNVERIFIED_EMAIL)
+		case errors.Is(err, server.ErrSSONoAccount):
+			return config.ssoLoginError(c, http.StatusUnauthorized, role, utils.ERROR_SSO_NO_ACCOUNT)
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR132500", err.Error()),
+		)
+	}
+
+	userRoles, err := config.Server.SyncSSORoles(ctx, userID, claims.Groups)
+	if err != nil {
+		if errors.Is(err, server.ErrSSONoRole) {
+			return config.ssoLoginError(c, http.StatusUnauthorized, role, utils.ERROR_SSO_NO_ROLE)
+		}
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR133500", err.Error()),
+		)
+	}
+
+	// the single role account goes to its own login, the same as Login
+	if otherLogin, ok := otherLoginURL(userRoles, role); ok {
+		return c.Redirect(http.StatusFound, otherLogin)
+	}
+
+	redirectURL := "/"
+	if role == utils.USER_ROLE_ADMIN {
+		redirectURL = "/admin/panel"
+	}
+
+	user, err := config.Server.Queries.GetUserById(ctx, userID)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR134500", err.Error()),
+		)
+	}
+
+	activeRole := server.DefaultActiveRole(userRoles, role)
+
+	required, err := config.isTwoFactorRequired(ctx, user, userRoles)
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR135500", err.Error()),
+		)
+	}
+
+	if required {
+		if err := config.beginTwoFactor(c, user.ID, redirectURL, activeRole); err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR136500", err.Error()),
+			)
+		}
+		redirectURL = twoFactorURL(role)
+	} else if err := config.startUserSession(c, user.ID, activeRole); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR137500", err.Error()),
+		)
+	}
+
+	log.Printf("SSO LOGIN: user %v from %v", user.ID, c.RealIP())
+
+	// not a 302, a redirect still counts as the provider's cross site
+	// navigation & the strict cookie just written wouldn't be sent
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "sso-redirect", Data{
+		"RedirectURL": redirectURL,
+	})
+}
@@ -2,9 +2,9 @@
 package web
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -39,8 +39,16 @@ func (config *webConfig) GetStudentSubmitPage(c echo.Context) error {
 		})
 	}
 
+	majors, err := config.Catalog.Majors(c.Request().Context())
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR68500", err.Error()),
+		)
+	}
+
 	return c.Render(http.StatusOK, "student-submission", Data{
-		"Major":      utils.MAJOR,
+		"Major":      majors,
 		"CSRF_Token": CSRFToken,
 	})
 }
@@ -73,6 +81,7 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 		ConfirmPassword string `validate:"password_constraints"`
 	}
 
+	var verifyEmail, verifyToken string
 	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
 		params := formParams{
 			Name:            c.FormValue("fullname"),
@@ -101,24 +110,8 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 			return err
 		}
 
-		// if free nim exists, get the smallest nim for the new created student
-		// and delete the record points to that free nim
-		nim, err := qtx.GetFreelistNim(ctx)
-		// checks if there is no free nim to be used
-		// simply generate from the student-nim
-		if err != nil {
-			nim, _ = qtx.GetCollectionMetaValue(ctx, "student-nim")
-			qtx.IncrementValueByname(ctx, "student-nim")
-			log.Println("\n\nstudent creation process...")
-		}
-
-		err = qtx.DeleteFreelistNim(ctx, nim)
-		if err != nil {
-			return err
-		}
-
 		// hash the user password
-		hashedPassword, err := utils.HashPassword(params.Password)
+		hashedPassword, err := config.Server.Passwords.Hash(params.Password)
 		if err != nil {
 			return err
 		}
@@ -132,7 +125,26 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 			return err
 		}
 
+		verifyToken, err = utils.NewEmailVerification(ctx, qtx, user.ID)
+		if err != nil {
+			return err
+		}
+		verifyEmail = user.Email
+
 		studentDat := c.Get("studentData").(*StudentData)
+		year := time.Now().Year()
+
+		nim, err := server.AllocateNim(ctx, qtx, studentDat.StudyPlan.Major, year)
+		if err != nil {
+			return err
+		}
+
+		// least full room of the major, locked until the tx ends
+		room, err := server.AssignRoom(ctx, qtx, studentDat.StudyPlan.Major)
+		if err != nil {
+			return err
+		}
+
 		student, err := qtx.CreateStudent(ctx, database.CreateStudentParams{
 			Nim:         nim,
 			Nip:         params.Nip,
@@ -140,9 +152,9 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 			Email:       params.Email,
 			PhoneNumber: params.PhoneNumber,
 			DateOfBirth: studentBirthDate,
-			Year:        int32(time.Now().Year()),
+			Year:        int32(year),
 			StudyPlanID: studentDat.StudyPlan.ID,
-			RoomID:      studentDat.Room.ID,
+			RoomID:      room.ID,
 			UserID:      user.ID,
 		})
 		if err != nil {
@@ -178,7 +190,12 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 			return err
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDENT_CREATE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			After:      student,
+		})
 	})
 	if err != nil {
 		c.Render(http.StatusUnprocessableEntity, "student-submission", Data{})
@@ -187,6 +204,8 @@ func (config *webConfig) CreateStudent(c echo.Context) error {
 		})
 	}
 
+	config.mailVerification(c, verifyEmail, verifyToken)
+
 	c.Response().Header().Set("HX-Redirect", "/login")
 	return c.NoContent(http.StatusCreated)
 }
@@ -212,28 +231,35 @@ func (config *webConfig) GetStudentProfile(c echo.Context) error {
 		)
 	}
 
-	switch claims.Roles[0] {
-	case utils.USER_ROLE_STUDENT:
-		if claims.UserID != paramUserID {
-			return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	student, err := query.GetStudentByUserId(ctx, paramUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Student Profile Not Found")
 		}
+		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	if allowed, _ := config.Server.Can(claims, "students", "view"); !allowed {
-		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
-	}
-
-	student, err := query.GetStudentByUserId(ctx, claims.UserID)
+	room, err := query.GetStudentRoomById(ctx, student.RoomID)
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	plan, err := query.GetStudyPlanById(ctx, student.StudyPlanID)
+	// the admin sees every student, the student its own profile
+	// (students:view:own), the room & major conditions are up to the roles
+	allowed, err := config.Server.Authorize(ctx, claims, "students", "view", server.Target{
+		OwnerID: student.UserID,
+		RoomID:  student.RoomID,
+		Major:   room.Major,
+	})
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 
-	room, err := query.GetStudentRoomById(ctx, student.RoomID)
+	if !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	plan, err := query.GetStudyPlanById(ctx, student.StudyPlanID)
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
@@ -253,19 +279,81 @@ func (config *webConfig) GetStudentProfile(c echo.Context) error {
 		"Student":  student,
 		"Plan":     plan,
 		"Room":     room,
-		"UserRole": claims.Roles[0],
+		"UserRole": claims.ActiveRole,
 	})
 }
 
-func (config *webConfig) GetUpdateStudentPage(c echo.Context) error {
+var (
+	errStudentNotFound = errors.New(utils.ERROR_STUDENT_NOT_FOUND)
+	errUnauthorized    = errors.New(utils.ERROR_USER_UNAUTHORIZED)
+)
+
+// getUpdatableStudent loads the student of the ":id" param (the user id of
+// the student) and checks students:update against it, the student role
+// holds students:update:own for its own profile
+func (config *webConfig) getUpdatableStudent(c echo.Context, claims *server.Claims) (database.Student, error) {
 	ctx := c.Request().Context()
-	query := config.Server.Queries
-	userID := c.Get("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return database.Student{}, errStudentNotFound
+	}
+
+	student, err := config.Server.Queries.GetStudentByUserId(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Student{}, errStudentNotFound
+		}
+		return database.Student{}, err
+	}
+
+	allowed, err := config.Server.Authorize(ctx, claims, "students", "update", server.Target{
+		OwnerID: student.UserID,
+	})
+	if err != nil {
+		return database.Student{}, err
+	}
+
+	if !allowed {
+		return database.Student{}, errUnauthorized
+	}
+
+	return student, nil
+}
+
+// renderStudentError answers the error of getUpdatableStudent
+func renderStudentError(c echo.Context, err error, code string) error {
+	switch {
+	case errors.Is(err, errStudentNotFound):
+		return c.Render(http.StatusNotFound, "error-message", Data{
+			"Message": err.Error(),
+		})
+	case errors.Is(err, errUnauthorized):
+		return c.Render(http.StatusForbidden, "unauthorized", Data{
+			"Message": err.Error(),
+		})
+	default:
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage(code, err.Error()),
+		)
+	}
+}
+
+func (config *webConfig) GetUpdateStudentPage(c echo.Context) error {
 	CRSFToken := c.Get("csrf").(string)
 
-	student, err := query.GetStudentByUserId(ctx, userID)
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR182500", ""),
+		)
+	}
+
+	student, err := config.getUpdatableStudent(c, claims)
 	if err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+		return renderStudentError(c, err, "ERR183500")
 	}
 
 	return c.Render(http.StatusOK, "update-student", Data{
@@ -278,15 +366,22 @@ func (config *webConfig) UpdateStudent(c echo.Context) error {
 	time.Sleep(200 * time.Millisecond)
 	ctx := c.Request().Context()
 	query := config.Server.Queries
-	userID := c.Get("user_id").(uuid.UUID)
 	type formParams struct {
 		Email       string `validate:"email_constraints,cheeky_sql_inject"`
 		PhoneNumber string `validate:"phone_constraints,cheeky_sql_inject"`
 	}
 
-	student, err := query.GetStudentByUserId(ctx, userID)
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR184500", ""),
+		)
+	}
+
+	student, err := config.getUpdatableStudent(c, claims)
 	if err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+		return renderStudentError(c, err, "ERR185500")
 	}
 
 	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
@@ -299,7 +394,7 @@ func (config *webConfig) UpdateStudent(c echo.Context) error {
 			return err
 		}
 
-		_, err = qtx.UpdateStudent(ctx, database.UpdateStudentParams{
+		updated, err := qtx.UpdateStudent(ctx, database.UpdateStudentParams{
 			ID:          student.ID,
 			Email:       params.Email,
 			PhoneNumber: params.PhoneNumber,
@@ -312,7 +407,13 @@ func (config *webConfig) UpdateStudent(c echo.Context) error {
 		// update updated_at for Last-Modified Header (caching)
 		qtx.UpdateCollectionMetaLastModified(ctx, "student-coll")
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDENT_UPDATE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			Before:     student,
+			After:      updated,
+		})
 	})
 	if err != nil {
 		c.Render(http.StatusUnprocessableEntity, "update-student", Data{})
@@ -321,7 +422,7 @@ func (config *webConfig) UpdateStudent(c echo.Context) error {
 		})
 	}
 
-	redirectURL := fmt.Sprintf("/students/%v/profile", student.ID)
+	redirectURL := fmt.Sprintf("/students/%v/profile", student.UserID)
 	c.Response().Header().Set("HX-Redirect", redirectURL)
 	return c.NoContent(http.StatusOK)
 }
@@ -359,10 +460,20 @@ func (config *webConfig) GetStudentsPage(c echo.Context) error {
 		return c.String(http.StatusUnprocessableEntity, err.Error())
 	}
 
-	studentsPageData["Rooms"] = utils.ROOM
-	studentsPageData["Majors"] = utils.MAJOR
+	rooms, err := config.Catalog.Rooms(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	majors, err := config.Catalog.Majors(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	studentsPageData["Rooms"] = rooms
+	studentsPageData["Majors"] = majors
 	studentsPageData["CSRF_Token"] = CSRFToken
-	studentsPageData["UserRole"] = claims.Roles[0]
+	studentsPageData["UserRole"] = claims.ActiveRole
 
 	// do validation based caching
 	lastModified, err := config.Server.Queries.GetCollectionMetaLastModified(ctx, "student-coll")
@@ -384,6 +495,21 @@ func (config *webConfig) GetStudentsPage(c echo.Context) error {
 func (config *webConfig) DeleteStudent(c echo.Context) error {
 	time.Sleep(300 * time.Millisecond)
 	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR186500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "students", "delete"); !allowed {
+		return c.Render(http.StatusForbidden, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
 	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
 		idStr := c.Param("id")
 		id, err := uuid.Parse(idStr)
@@ -413,8 +539,8 @@ func (config *webConfig) DeleteStudent(c echo.Context) error {
 			return err
 		}
 
-		// simply, add the nim to freelist, if there is student deletion
-		if err = qtx.AddToFreelist(ctx, student.Nim); err != nil {
+		// the nim goes back to the pool of its cohort
+		if err = server.ReleaseNim(ctx, qtx, student.Nim, studentPlan.Major, int(student.Year)); err != nil {
 			return err
 		}
 
@@ -423,7 +549,12 @@ func (config *webConfig) DeleteStudent(c echo.Context) error {
 			return err
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDENT_DELETE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			Before:     student,
+		})
 	})
 	if err != nil {
 		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
@@ -0,0 +1,485 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// StudyPlanData is one row of the admin study plan panel
+type StudyPlanData struct {
+	StudyPlan     database.StudyPlan
+	StudentsCount int64
+	Courses       []database.GetStudyPlanCoursesRow
+}
+
+func (config *webConfig) GetStudyPlanPage(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR59500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "studyPlan", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR60500", ""),
+		)
+	}
+
+	student, err := query.GetStudentByUserId(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	plan, err := query.GetStudyPlanById(ctx, student.StudyPlanID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	courses, err := query.GetCoursesByStudyPlanID(ctx, plan.ID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	// all the enrollments of the student, across semesters (history)
+	enrollments, err := query.GetEnrollmentsByStudentID(ctx, student.ID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	enrolled := map[uuid.UUID]bool{}
+	for _, e := range enrollments {
+		if e.Semester == plan.Semester && e.Status == utils.ENROLLMENT_STATUS_ACTIVE {
+			enrolled[e.CourseID] = true
+		}
+	}
+
+	return c.Render(http.StatusOK, "study-plan-page", Data{
+		"CSRF_Token":  CSRFToken,
+		"UserID":      claims.UserID,
+		"UserRole":    utils.USER_ROLE_STUDENT,
+		"Plan":        plan,
+		"Courses":     courses,
+		"Enrolled":    enrolled,
+		"Enrollments": enrollments,
+	})
+}
+
+func (config *webConfig) EnrollCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR61500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "enrollments", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		student, err := qtx.GetStudentByUserId(ctx, claims.UserID)
+		if err != nil {
+			return err
+		}
+
+		plan, err := qtx.GetStudyPlanById(ctx, student.StudyPlanID)
+		if err != nil {
+			return err
+		}
+
+		// the student can only take the course of their current study plan
+		n, err := qtx.CountStudyPlanCourse(ctx, database.CountStudyPlanCourseParams{
+			StudyPlanID: plan.ID,
+			CourseID:    courseID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return errors.New(utils.ERROR_COURSE_NOT_IN_STUDY_PLAN)
+		}
+
+		if err = qtx.EnrollStudent(ctx, database.EnrollStudentParams{
+			StudentID:   student.ID,
+			CourseID:    courseID,
+			StudyPlanID: plan.ID,
+			Semester:    plan.Semester,
+		}); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ENROLLMENT_CREATE,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			After:      Data{"CourseID": courseID, "StudyPlanID": plan.ID},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/study-plan")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (config *webConfig) DropCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR62500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "enrollments", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	}
+
+	courseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	student, err := query.GetStudentByUserId(ctx, claims.UserID)
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		if err := qtx.DropEnrollment(ctx, database.DropEnrollmentParams{
+			StudentID: student.ID,
+			CourseID:  courseID,
+		}); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_ENROLLMENT_DROP,
+			TargetType: "student",
+			TargetID:   student.ID.String(),
+			Before:     Data{"CourseID": courseID},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/study-plan")
+	return c.NoContent(http.StatusOK)
+}
+
+// NOTE: admin level utilsFunc
+
+func (config *webConfig) GetStudyPlansPage(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_getstudyplans:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR63500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "studyPlans", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	plans, err := query.GetStudyPlanAll(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	studentsCount, err := query.GetStudyPlanStudentsCount(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	planCourses, err := query.GetStudyPlanCourses(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	courses, err := query.GetCoursesActive(ctx)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	studyPlans := make([]StudyPlanData, len(plans))
+	for i, plan := range plans {
+		studyPlans[i].StudyPlan = plan
+		for _, sc := range studentsCount {
+			if sc.StudyPlanID == plan.ID {
+				studyPlans[i].StudentsCount = sc.Count
+			}
+		}
+		for _, pc := range planCourses {
+			if pc.StudyPlanID == plan.ID {
+				studyPlans[i].Courses = append(studyPlans[i].Courses, pc)
+			}
+		}
+	}
+
+	return c.Render(http.StatusOK, "db-study-plans-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"StudyPlans": studyPlans,
+		"Courses":    courses,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) AddStudyPlanCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR64500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "studyPlans", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	courseID, err := uuid.Parse(c.FormValue("course_id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.AddCourseToStudyPlan(ctx, database.AddCourseToStudyPlanParams{
+			StudyPlanID: planID,
+			CourseID:    courseID,
+		}); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDY_PLAN_ADD_COURSE,
+			TargetType: "studyPlan",
+			TargetID:   planID.String(),
+			After:      Data{"CourseID": courseID},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/study-plans")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (config *webConfig) RemoveStudyPlanCourse(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR65500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "studyPlans", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	courseID, err := uuid.Parse(c.Param("courseId"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.RemoveCourseFromStudyPlan(ctx, database.RemoveCourseFromStudyPlanParams{
+			StudyPlanID: planID,
+			CourseID:    courseID,
+		}); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDY_PLAN_REMOVE_COURSE,
+			TargetType: "studyPlan",
+			TargetID:   planID.String(),
+			Before:     Data{"CourseID": courseID},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/study-plans")
+	return c.NoContent(http.StatusOK)
+}
+
+// PromoteStudyPlan moves every student of the study plan (cohort) to the
+// next semester of the same major. The active enrollments of the semester
+// are closed as completed, so they stay as the student's history
+func (config *webConfig) PromoteStudyPlan(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, Contact Support with code:ERR66500",
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "studyPlans", "promote"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		// lock the plan, two promotions of the same cohort can't run together
+		plan, err := qtx.LockStudyPlanById(ctx, planID)
+		if err != nil {
+			return err
+		}
+
+		nextPlan, err := qtx.GetStudyPlan(ctx, database.GetStudyPlanParams{
+			Semester: plan.Semester + 1,
+			Major:    plan.Major,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("error: there is no study plan for %v semester %d", plan.Major, plan.Semester+1)
+			}
+			return err
+		}
+
+		if err = qtx.CompleteEnrollmentsByStudyPlan(ctx, plan.ID); err != nil {
+			return err
+		}
+
+		promoted, err := qtx.PromoteStudentsStudyPlan(ctx, database.PromoteStudentsStudyPlanParams{
+			NextStudyPlanID: nextPlan.ID,
+			StudyPlanID:     plan.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if promoted == 0 {
+			return errors.New("error: there is no student in this study plan")
+		}
+
+		// update updated_at for Last-Modified Header (caching)
+		if err = qtx.UpdateCollectionMetaLastModified(ctx, "student-coll"); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_STUDY_PLAN_PROMOTE,
+			TargetType: "studyPlan",
+			TargetID:   plan.ID.String(),
+			Before:     Data{"StudyPlanID": plan.ID, "Semester": plan.Semester},
+			After:      Data{"StudyPlanID": nextPlan.ID, "Semester": nextPlan.Semester, "Promoted": promoted},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/study-plans")
+	return c.NoContent(http.StatusOK)
+}
@@ -1,39 +1,107 @@
 package web
 
 import (
-	"fmt"
-	"io"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/queue"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
-func (config *webConfig) GetCoursePage(c echo.Context) error {
+func (config *webConfig) GetTeacherProfile(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	paramUserID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
 	claims, ok := c.Get("claims").(*server.Claims)
 	if !ok {
-		return c.String(http.StatusInternalServerError, "Please Contact Support with code:76500")
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR52500", ""),
+		)
+	}
+
+	// teacher sees its own profile (teachers:view:own), admin sees all of them
+	allowed, err := config.Server.Authorize(ctx, claims, "teachers", "view", server.Target{
+		OwnerID: paramUserID,
+	})
+	if err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR152500", err.Error()),
+		)
 	}
 
-	if allowed, _ := config.Server.Can(claims, "coursePage", "view"); !allowed {
+	if !allowed {
 		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
 	}
 
-	return c.Render(http.StatusOK, "course-page", Data{})
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR53500", ""),
+		)
+	}
+
+	teacher, err := query.GetTeacherByUserId(ctx, paramUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.String(http.StatusNotFound, "Teacher Profile Not Found")
+		}
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	subjects := []string{}
+	if teacher.Subjects != "" {
+		subjects = strings.Split(teacher.Subjects, ",")
+	}
+
+	// validation based caching
+	lastModified := teacher.UpdatedAt
+
+	valid, ETag := IsCacheValid(c, lastModified)
+	if valid {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	c.Response().Header().Set("ETag", ETag)
+	c.Response().Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	return c.Render(http.StatusOK, "teacher-profile", Data{
+		"CSRF_Token": CSRFToken,
+		"Teacher":    teacher,
+		"Subjects":   subjects,
+		"UserRole":   claims.ActiveRole,
+	})
 }
 
-func (config *webConfig) CreateCourse(c echo.Context) error {
-	context := c.Request().Context()
+// NOTE: admin level utilsFunc
+
+func (config *webConfig) GetTeachersPage(c echo.Context) error {
+	ctx := c.Request().Context()
 	query := config.Server.Queries
+
 	CSRFToken, ok := c.Get("csrf").(string)
 	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR74500", ""),
+			"Internal Server Error, at debug_block_getteachers:1",
 		)
 	}
 
@@ -41,87 +109,308 @@ func (config *webConfig) CreateCourse(c echo.Context) error {
 	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR44500", ""),
+			"Internal Server Error, Contact Support with code:ERR54500",
 		)
 	}
 
-	if allowed, _ := config.Server.Can(claims, "courses", "create"); !allowed {
-		return c.Render(http.StatusUnauthorized, "unauthorized", Data{})
+	if allowed, _ := config.Server.Can(claims, "adminPanelPages", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
 	}
 
-	type formParams struct {
-		Title     string
-		Desc      string
-		CreatedAt string
+	var queryParams struct {
+		Search string `query:"search" validate:"omitempty,nochars,cheeky_sql_inject"`
 	}
 
-	params := &formParams{
-		Title:     c.FormValue("course_title"),
-		Desc:      c.FormValue("course_desc"),
-		CreatedAt: c.FormValue("course_date"),
+	if err := c.Bind(&queryParams); err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
 	}
 
-	if err := c.Validate(params); err != nil {
-		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
-			"Message": utils.ERROR_INVALID_INPUT_DATA,
-		})
+	if err := c.Validate(&queryParams); err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
 	}
 
-	// Generate ID to identified the file from storage
-	// Store it to the DB with corresponds course entry
-	coursesStoragePath := os.Getenv("course_storage_path")
-	courseFileID := fmt.Sprintf("%s_id_%v", params.Title, time.Now().Unix())
+	var teachers []database.Teacher
+	var err error
+
+	switch _, numErr := strconv.Atoi(queryParams.Search); {
+	case queryParams.Search == "":
+		teachers, err = query.GetTeacherAll(ctx)
+	case numErr != nil:
+		teachers, err = query.GetTeacherByNameOrNip(ctx, database.GetTeacherByNameOrNipParams{
+			Name: "%" + strings.ToLower(queryParams.Search) + "%",
+			Nip:  "%%",
+		})
+	default:
+		teachers, err = query.GetTeacherByNameOrNip(ctx, database.GetTeacherByNameOrNipParams{
+			Name: "%%",
+			Nip:  "%" + queryParams.Search + "%",
+		})
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
 
-	file, err := c.FormFile("course")
+	// do validation based caching
+	lastModified, err := query.GetCollectionMetaLastModified(ctx, "teacher-coll")
 	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	if queryParams.Search == "" {
+		valid, ETag := IsCacheValid(c, lastModified)
+		if valid {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		c.Response().Header().Set("ETag", ETag)
+		c.Response().Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		c.Response().Header().Set("Cache-Control", "no-cache")
+	}
+
+	return c.Render(http.StatusOK, "db-teachers-panel", Data{
+		"CSRF_Token": CSRFToken,
+		"Teachers":   teachers,
+		"UserRole":   claims.ActiveRole,
+	})
+}
+
+func (config *webConfig) GetTeacherSubmitPage(c echo.Context) error {
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR88500", err.Error()),
+			"Internal Server Error, Contact Support with code:ERR55500",
 		)
 	}
 
-	defer c.Request().MultipartForm.RemoveAll()
-
-	src, err := file.Open()
-	if err != nil {
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR87500", err.Error()),
+			"Internal Server Error, Contact Support with code:ERR56500",
 		)
 	}
 
-	defer src.Close()
+	if allowed, _ := config.Server.Can(claims, "teachers", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
 
-	dst, err := os.Create(coursesStoragePath + courseFileID)
-	if err != nil {
+	return c.Render(http.StatusOK, "teacher-submission", Data{
+		"CSRF_Token": CSRFToken,
+	})
+}
+
+func (config *webConfig) CreateTeacher(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR89500", err.Error()),
+			"Internal Server Error, Contact Support with code:ERR57500",
 		)
 	}
 
-	defer dst.Close()
+	if allowed, _ := config.Server.Can(claims, "teachers", "create"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	type formParams struct {
+		Name            string `validate:"name_constraints,cheeky_sql_inject"`
+		Email           string `validate:"email_constraints,cheeky_sql_inject"`
+		PhoneNumber     string `validate:"phone_constraints"`
+		Nip             string `validate:"nip_constraints"`
+		DateOfBirth     string `validate:"cheeky_sql_inject"`
+		Subjects        string `validate:"max=255,cheeky_sql_inject"`
+		Password        string `validate:"password_constraints"`
+		ConfirmPassword string `validate:"password_constraints"`
+	}
+
+	var verifyEmail, verifyToken string
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		params := formParams{
+			Name:            c.FormValue("fullname"),
+			Email:           c.FormValue("email"),
+			PhoneNumber:     c.FormValue("phone"),
+			Nip:             c.FormValue("nip"),
+			DateOfBirth:     c.FormValue("birthdate"),
+			Subjects:        c.FormValue("subjects"),
+			Password:        c.FormValue("password"),
+			ConfirmPassword: c.FormValue("confirm-password"),
+		}
+
+		if err := c.Validate(&params); err != nil {
+			return err
+		}
+
+		if params.Password != params.ConfirmPassword {
+			return errors.New(utils.ERROR_INVALID_CONFIRM_PASSWORD)
+		}
 
-	// COPIES TO STAGING STORAGE (temp)
-	if _, err = io.Copy(dst, src); err != nil {
-		os.Remove(coursesStoragePath + courseFileID)
+		if !utils.IsNIPValid(params.Nip, params.DateOfBirth) {
+			return errors.New(utils.ERROR_INVALID_NIP)
+		}
+
+		teacherBirthDate, err := time.Parse(time.DateOnly, params.DateOfBirth)
+		if err != nil {
+			return err
+		}
+
+		// subjects are kept as comma separated, normalize the spacing
+		subjects := []string{}
+		for subject := range strings.SplitSeq(params.Subjects, ",") {
+			if subject = strings.TrimSpace(subject); subject != "" {
+				subjects = append(subjects, strings.ToLower(subject))
+			}
+		}
+
+		// hash the user password
+		hashedPassword, err := config.Server.Passwords.Hash(params.Password)
+		if err != nil {
+			return err
+		}
+
+		// doing user & teacher creation
+		user, err := qtx.CreateUser(ctx, database.CreateUserParams{
+			Email:        params.Email,
+			PasswordHash: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		verifyToken, err = utils.NewEmailVerification(ctx, qtx, user.ID)
+		if err != nil {
+			return err
+		}
+		verifyEmail = user.Email
+
+		teacher, err := qtx.CreateTeacher(ctx, database.CreateTeacherParams{
+			Nip:         params.Nip,
+			Name:        strings.ToLower(params.Name),
+			Email:       params.Email,
+			PhoneNumber: params.PhoneNumber,
+			DateOfBirth: teacherBirthDate,
+			Subjects:    strings.Join(subjects, ","),
+			UserID:      user.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// assign teacher role
+		_, err = qtx.CreateUserRoles(ctx, database.CreateUserRolesParams{
+			UserID: user.ID,
+			Role:   utils.USER_ROLE_TEACHER,
+		})
+		if err != nil {
+			return err
+		}
+
+		// update updated_at for Last-Modified Header (caching)
+		if err = qtx.UpdateCollectionMetaLastModified(ctx, "teacher-coll"); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_TEACHER_CREATE,
+			TargetType: "teacher",
+			TargetID:   teacher.ID.String(),
+			After:      teacher,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ValidationErrorMsg(err.Error()),
+		})
+	}
+
+	config.mailVerification(c, verifyEmail, verifyToken)
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/teachers")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (config *webConfig) DeleteTeacher(c echo.Context) error {
+	time.Sleep(300 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
 		return c.String(
 			http.StatusInternalServerError,
-			utils.InternalServerErrorMessage("ERR99500", err.Error()),
+			"Internal Server Error, Contact Support with code:ERR58500",
 		)
 	}
 
-	if err = utils.WithTX(context, config.Server.DB, query, func(qtx *database.Queries) error {
-		return nil
-	}); err != nil {
-		os.Remove(coursesStoragePath + courseFileID)
-		return c.Render(http.StatusInternalServerError, "error-message", Data{
-			"CSRF_Token": CSRFToken,
+	if allowed, _ := config.Server.Can(claims, "teachers", "delete"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
 		})
 	}
 
-	// Send Message to Queue to process moves the file to permanent storge (validation file involved)
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
 
-	c.Response().Header().Set("HX-Redirect", "/courses/create")
-	return c.NoContent(http.StatusCreated)
+	var fileKeys []string
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		teacher, err := qtx.DeleteTeacherByUserId(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		courses, err := qtx.GetCoursesByTeacherID(ctx, teacher.ID)
+		if err != nil {
+			return err
+		}
+
+		// the file may still be staged when the upload job hasn't run yet
+		for _, course := range courses {
+			fileKeys = append(fileKeys, queue.CourseStagingKey(course.FileID), queue.CourseFileKey(course.FileID))
+		}
+
+		// the courses of the teacher are gone along with the user (cascade)
+		if err = qtx.DeleteUserByID(ctx, teacher.UserID); err != nil {
+			return err
+		}
+
+		// update updated_at for Last-Modified Header (caching)
+		if err = qtx.UpdateCollectionMetaLastModified(ctx, "teacher-coll"); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_TEACHER_DELETE,
+			TargetType: "teacher",
+			TargetID:   teacher.ID.String(),
+			Before:     teacher,
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	// the rows are gone, the files only after the commit. A failed delete
+	// leaves an orphan file, not a course without its file
+	for _, key := range fileKeys {
+		if err := config.Server.Storage.Delete(ctx, key); err != nil {
+			log.Println("COURSE FILE DELETE FAILED:", key, err)
+		}
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/teachers")
+	return c.NoContent(http.StatusOK)
 }
@@ -0,0 +1,671 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/totp"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+)
+
+// the pending login lives in the cookie session between the password
+// and the code, nothing goes to the sessions table before the code is valid
+const (
+	twoFactorUserKey     = "2fa_user_id"
+	twoFactorExpireKey   = "2fa_expire_at"
+	twoFactorRedirectKey = "2fa_redirect"
+	twoFactorRoleKey     = "2fa_active_role"
+	twoFactorSecretKey   = "2fa_secret"
+	twoFactorSetupKey    = "2fa_setup_secret"
+)
+
+func twoFactorURL(role string) string {
+	if role == utils.USER_ROLE_ADMIN {
+		return "/admin/login/2fa"
+	}
+	return "/login/2fa"
+}
+
+func loginURL(role string) string {
+	if role == utils.USER_ROLE_ADMIN {
+		return "/admin/login"
+	}
+	return "/login"
+}
+
+func (config *webConfig) isTwoFactorRequired(ctx context.Context, user database.User, roles []string) (bool, error) {
+	if user.TotpEnabled {
+		return true, nil
+	}
+
+	n, err := config.Server.Queries.CountTOTPRequiredRoles(ctx, roles)
+	return n > 0, err
+}
+
+func (config *webConfig) beginTwoFactor(c echo.Context, userID uuid.UUID, redirectURL, activeRole string) error {
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return err
+	}
+
+	clearTwoFactor(session)
+	session.Values[twoFactorUserKey] = userID.String()
+	session.Values[twoFactorExpireKey] = time.Now().Add(utils.TWO_FACTOR_PENDING_TTL).Unix()
+	session.Values[twoFactorRedirectKey] = redirectURL
+	session.Values[twoFactorRoleKey] = activeRole
+
+	return session.Save(c.Request(), c.Response())
+}
+
+func clearTwoFactor(session *sessions.Session) {
+	delete(session.Values, twoFactorUserKey)
+	delete(session.Values, twoFactorExpireKey)
+	delete(session.Values, twoFactorRedirectKey)
+	delete(session.Values, twoFactorRoleKey)
+	delete(session.Values, twoFactorSecretKey)
+}
+
+// pendingTwoFactor returns the user who passed the password step,
+// false when there is none or it's expired
+func (config *webConfig) pendingTwoFactor(c echo.Context) (*sessions.Session, uuid.UUID, bool) {
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return nil, uuid.Nil, false
+	}
+
+	userIDStr, _ := session.Values[twoFactorUserKey].(string)
+	expireAt, _ := session.Values[twoFactorExpireKey].(int64)
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil || time.Now().Unix() > expireAt {
+		return session, uuid.Nil, false
+	}
+
+	return session, userID, true
+}
+
+// verifySecondFactor takes the totp code or one of the recovery codes.
+// The totp step is stored, so the same code can't be used twice
+func (config *webConfig) verifySecondFactor(ctx context.Context, user database.User, code string) error {
+	query := config.Server.Queries
+
+	if step, ok := totp.Validate(user.TotpSecret, code, time.Now(), utils.TOTP_SKEW); ok {
+		n, err := query.UpdateUserTOTPStep(ctx, database.UpdateUserTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New(utils.ERROR_INVALID_TOTP_CODE)
+		}
+		return nil
+	}
+
+	n, err := query.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: tokens.Hash(totp.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New(utils.ERROR_INVALID_TOTP_CODE)
+	}
+
+	return nil
+}
+
+// enableTwoFactor stores the confirmed secret and replaces the recovery
+// codes, the plain codes are only shown once
+func (config *webConfig) enableTwoFactor(c echo.Context, userID uuid.UUID, secret string, step int64) ([]string, error) {
+	ctx := c.Request().Context()
+	codes, err := totp.GenerateRecoveryCodes(utils.RECOVERY_CODES_COUNT)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.EnableUserTOTP(ctx, database.EnableUserTOTPParams{
+			ID:           userID,
+			TotpSecret:   secret,
+			TotpLastStep: step,
+		}); err != nil {
+			return err
+		}
+
+		if err := replaceRecoveryCodes(ctx, qtx, userID, codes); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_TWO_FACTOR_ENABLE,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Before:     Data{"TotpEnabled": false},
+			After:      Data{"TotpEnabled": true},
+		})
+	})
+
+	return codes, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, qtx *database.Queries, userID uuid.UUID, codes []string) error {
+	if err := qtx.DeleteRecoveryCodesByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if err := qtx.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: tokens.Hash(code),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (config *webConfig) GetTwoFactorPage(role string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		CSRFToken, ok := c.Get("csrf").(string)
+		if !ok {
+			return c.String(
+				http.StatusInternalServerError,
+				"Internal Server Error, at debug_block_twofactor:1",
+			)
+		}
+
+		session, userID, ok := config.pendingTwoFactor(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, loginURL(role))
+		}
+
+		user, err := config.Server.Queries.GetUserById(ctx, userID)
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR93500", err.Error()),
+			)
+		}
+
+		data := Data{
+			"Role":       role,
+			"CSRF_Token": CSRFToken,
+			"Action":     twoFactorURL(role),
+			"Enroll":     !user.TotpEnabled,
+		}
+
+		// the role requires 2fa but the user has none yet, enroll right here
+		if !user.TotpEnabled {
+			secret, _ := session.Values[twoFactorSecretKey].(string)
+			if secret == "" {
+				if secret, err = totp.GenerateSecret(); err != nil {
+					return c.String(
+						http.StatusInternalServerError,
+						utils.InternalServerErrorMessage("ERR94500", err.Error()),
+					)
+				}
+
+				session.Values[twoFactorSecretKey] = secret
+				if err := session.Save(c.Request(), c.Response()); err != nil {
+					return c.String(http.StatusInternalServerError, err.Error())
+				}
+			}
+
+			data["Secret"] = secret
+			data["URI"] = totp.ProvisioningURI(utils.TOTP_ISSUER, user.Email, secret)
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.Render(http.StatusOK, "two-factor-page", data)
+	}
+}
+
+func (config *webConfig) VerifyTwoFactor(role string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		ctx := c.Request().Context()
+
+		session, userID, ok := config.pendingTwoFactor(c)
+		if !ok {
+			return c.Render(http.StatusUnauthorized, "error-message", Data{
+				"Message": utils.ERROR_TWO_FACTOR_EXPIRED,
+			})
+		}
+
+		redirectURL, _ := session.Values[twoFactorRedirectKey].(string)
+		activeRole, _ := session.Values[twoFactorRoleKey].(string)
+		code := c.FormValue("code")
+
+		user, err := config.Server.Queries.GetUserById(ctx, userID)
+		if err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				utils.InternalServerErrorMessage("ERR95500", err.Error()),
+			)
+		}
+
+		if !user.TotpEnabled {
+			secret, _ := session.Values[twoFactorSecretKey].(string)
+			step, ok := totp.Validate(secret, code, time.Now(), utils.TOTP_SKEW)
+			if secret == "" || !ok {
+				return c.Render(http.StatusUnauthorized, "error-message", Data{
+					"Message": utils.ERROR_INVALID_TOTP_CODE,
+				})
+			}
+
+			codes, err := config.enableTwoFactor(c, user.ID, secret, step)
+			if err != nil {
+				return c.String(
+					http.StatusInternalServerError,
+					utils.InternalServerErrorMessage("ERR96500", err.Error()),
+				)
+			}
+
+			if err := config.startUserSession(c, user.ID, activeRole); err != nil {
+				return c.String(
+					http.StatusInternalServerError,
+					fmt.Sprintf("Internal Server Error, %v", err.Error()),
+				)
+			}
+
+			return c.Render(http.StatusOK, "recovery-codes", Data{
+				"Codes":       codes,
+				"ContinueURL": redirectURL,
+			})
+		}
+
+		_, locked, err := config.Server.LoginLockedUntil(ctx, user.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+
+		if locked {
+			return c.Render(http.StatusTooManyRequests, "error-message", Data{
+				"Message": utils.ERROR_ACCOUNT_LOCKED,
+			})
+		}
+
+		if err := config.verifySecondFactor(ctx, user, code); err != nil {
+			// the wrong code counts the same as the wrong password
+			delay, recErr := config.Server.RecordLoginFailure(ctx, user.ID, c.RealIP())
+			if recErr != nil {
+				log.Println(recErr)
+			}
+			time.Sleep(delay)
+
+			return c.Render(http.StatusUnauthorized, "error-message", Data{
+				"Message": err.Error(),
+			})
+		}
+
+		if err := config.startUserSession(c, user.ID, activeRole); err != nil {
+			return c.String(
+				http.StatusInternalServerError,
+				fmt.Sprintf("Internal Server Error, %v", err.Error()),
+			)
+		}
+
+		c.Response().Header().Set("HX-Redirect", redirectURL)
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// NOTE: account level utilsFunc
+
+func (config *webConfig) GetAccountSecurityPage(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	CSRFToken, ok := c.Get("csrf").(string)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			"Internal Server Error, at debug_block_accountsecurity:1",
+		)
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR97500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "view"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	user, err := query.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	recoveryLeft, err := query.CountUnusedRecoveryCodes(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	required, err := query.CountTOTPRequiredRoles(ctx, claims.Roles)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Render(http.StatusOK, "account-security", Data{
+		"CSRF_Token":   CSRFToken,
+		"UserID":       claims.UserID,
+		"UserRole":     claims.ActiveRole,
+		"Email":        user.Email,
+		"TotpEnabled":  user.TotpEnabled,
+		"RecoveryLeft": recoveryLeft,
+		"Required":     required > 0,
+	})
+}
+
+func (config *webConfig) SetupTwoFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR98500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	CSRFToken, _ := c.Get("csrf").(string)
+
+	user, err := config.Server.Queries.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	// kept in the cookie until the first code confirms the app has it
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	session.Values[twoFactorSetupKey] = secret
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Render(http.StatusOK, "two-factor-setup", Data{
+		"CSRF_Token": CSRFToken,
+		"Action":     "/account/2fa/enable",
+		"Secret":     secret,
+		"URI":        totp.ProvisioningURI(utils.TOTP_ISSUER, user.Email, secret),
+	})
+}
+
+func (config *webConfig) EnableTwoFactor(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR100500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	session, err := config.store.Get(c.Request(), config.sessionName)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	secret, _ := session.Values[twoFactorSetupKey].(string)
+	step, ok := totp.Validate(secret, c.FormValue("code"), time.Now(), utils.TOTP_SKEW)
+	if secret == "" || !ok {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_TOTP_CODE,
+		})
+	}
+
+	codes, err := config.enableTwoFactor(c, claims.UserID, secret, step)
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	delete(session.Values, twoFactorSetupKey)
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Render(http.StatusOK, "recovery-codes", Data{
+		"Codes":       codes,
+		"ContinueURL": "/account/security",
+	})
+}
+
+func (config *webConfig) DisableTwoFactor(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR102500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	required, err := query.CountTOTPRequiredRoles(ctx, claims.Roles)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	if required > 0 {
+		return c.Render(http.StatusForbidden, "error-message", Data{
+			"Message": utils.ERROR_TWO_FACTOR_REQUIRED,
+		})
+	}
+
+	user, err := query.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := config.verifySecondFactor(ctx, user, c.FormValue("code")); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		if err := qtx.DisableUserTOTP(ctx, user.ID); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteRecoveryCodesByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_TWO_FACTOR_DISABLE,
+			TargetType: "user",
+			TargetID:   user.ID.String(),
+			Before:     Data{"TotpEnabled": true},
+			After:      Data{"TotpEnabled": false},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/account/security")
+	return c.NoContent(http.StatusOK)
+}
+
+func (config *webConfig) RegenerateRecoveryCodes(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+	query := config.Server.Queries
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR104500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "account", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	user, err := query.GetUserById(ctx, claims.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	if !user.TotpEnabled {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": utils.ERROR_INVALID_INPUT_DATA,
+		})
+	}
+
+	if err := config.verifySecondFactor(ctx, user, c.FormValue("code")); err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(utils.RECOVERY_CODES_COUNT)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	err = utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
+		return replaceRecoveryCodes(ctx, qtx, user.ID, codes)
+	})
+	if err != nil {
+		return c.Render(http.StatusInternalServerError, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	return c.Render(http.StatusOK, "recovery-codes", Data{
+		"Codes":       codes,
+		"ContinueURL": "/account/security",
+	})
+}
+
+// NOTE: admin level utilsFunc
+
+// UpdateRolePolicyTOTP turns the 2fa requirement of the role on/off. On
+// turning it on, the sessions of the role's users without 2fa are dropped,
+// they go through the enrollment on the next login
+func (config *webConfig) UpdateRolePolicyTOTP(c echo.Context) error {
+	time.Sleep(200 * time.Millisecond)
+	ctx := c.Request().Context()
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR106500", ""),
+		)
+	}
+
+	if allowed, _ := config.Server.Can(claims, "security", "update"); !allowed {
+		return c.Render(http.StatusUnauthorized, "unauthorized", Data{
+			"Message": utils.ERROR_USER_UNAUTHORIZED,
+		})
+	}
+
+	role := c.Param("role")
+	require := c.FormValue("require_totp") == "on"
+
+	// the users of the role who can't pass the new policy yet, logged out
+	// once it's committed
+	var loggedOut []uuid.UUID
+	err := utils.WithTX(ctx, config.Server.DB, config.Server.Queries, func(qtx *database.Queries) error {
+		if err := qtx.UpdateRolePolicyTOTP(ctx, database.UpdateRolePolicyTOTPParams{
+			Role:        role,
+			RequireTotp: require,
+		}); err != nil {
+			return err
+		}
+
+		if require {
+			var err error
+			if loggedOut, err = qtx.GetUserIDsOfRoleWithoutTOTP(ctx, role); err != nil {
+				return err
+			}
+		}
+
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_TWO_FACTOR_POLICY_UPDATE,
+			TargetType: "role",
+			TargetID:   role,
+			Before:     Data{"RequireTotp": !require},
+			After:      Data{"RequireTotp": require},
+		})
+	})
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
+			"Message": err.Error(),
+		})
+	}
+
+	if err := config.Server.Sessions.RevokeUsers(ctx, loggedOut); err != nil {
+		return c.String(
+			http.StatusInternalServerError,
+			utils.InternalServerErrorMessage("ERR180500", err.Error()),
+		)
+	}
+
+	c.Response().Header().Set("HX-Redirect", "/admin/panel/security")
+	return c.NoContent(http.StatusOK)
+}
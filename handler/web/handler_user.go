@@ -47,7 +47,7 @@ func (config *webConfig) GetUsersPage(c echo.Context) error {
 	return c.Render(http.StatusOK, "db-users-panel", Data{
 		"CSRF_Token": CSRFToken,
 		"Users":      users,
-		"UserRole":   claims.Roles[0],
+		"UserRole":   claims.ActiveRole,
 	})
 }
 
@@ -90,12 +90,13 @@ func (config *webConfig) CreateUser(c echo.Context) error {
 		Role:     c.FormValue("roles"),
 	}
 
+	var verifyEmail, verifyToken string
 	err := utils.WithTX(ctx, config.Server.DB, query, func(qtx *database.Queries) error {
 		if err := c.Validate(params); err != nil {
 			return err
 		}
 
-		passwordHashed, err := utils.HashPassword(params.Password)
+		passwordHashed, err := config.Server.Passwords.Hash(params.Password)
 		if err != nil {
 			return err
 		}
@@ -108,6 +109,12 @@ func (config *webConfig) CreateUser(c echo.Context) error {
 			return err
 		}
 
+		verifyToken, err = utils.NewEmailVerification(ctx, qtx, user.ID)
+		if err != nil {
+			return err
+		}
+		verifyEmail = user.Email
+
 		// if role "superuser", assign with two user-type
 		// performance-wise might be bad (iterate those user-type)
 		switch params.Role {
@@ -135,7 +142,12 @@ func (config *webConfig) CreateUser(c echo.Context) error {
 
 		}
 
-		return nil
+		return utils.Audit(c, qtx, utils.AuditEvent{
+			Action:     utils.AUDIT_USER_CREATE,
+			TargetType: "user",
+			TargetID:   user.ID.String(),
+			After:      Data{"Email": user.Email, "Role": params.Role},
+		})
 	})
 	if err != nil {
 		return c.Render(http.StatusUnprocessableEntity, "error-message", Data{
@@ -144,6 +156,8 @@ func (config *webConfig) CreateUser(c echo.Context) error {
 		})
 	}
 
+	config.mailVerification(c, verifyEmail, verifyToken)
+
 	c.Response().Header().Set("HX-Redirect", "/admin/panel/users")
 	return c.NoContent(http.StatusOK)
 }
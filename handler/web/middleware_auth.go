@@ -6,14 +6,23 @@ import (
 	"slices"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
 )
 
 var skipperEndpoint = []string{
 	"/login",
 	"/admin/login",
+	"/forgot-password",
+	"/admin/forgot-password",
+	"/reset-password",
+	"/login/2fa",
+	"/admin/login/2fa",
+	"/login/sso",
+	"/admin/login/sso",
+	"/login/sso/callback",
+	"/verify-email",
+	"/verify-email/resend",
 }
 
 func (config *webConfig) MiddlewareSession(next echo.HandlerFunc) echo.HandlerFunc {
@@ -35,7 +44,7 @@ func (config *webConfig) MiddlewareSession(next echo.HandlerFunc) echo.HandlerFu
 
 func (config *webConfig) MiddlewareAuthN(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		query := config.Server.Queries
+		sessions := config.Server.Sessions
 		ctx := c.Request().Context()
 		reqPath := c.Path()
 
@@ -46,52 +55,26 @@ func (config *webConfig) MiddlewareAuthN(next echo.HandlerFunc) echo.HandlerFunc
 				return c.String(http.StatusInternalServerError, err.Error())
 			}
 
-			sessionID, ok := session.Values["session_id"].(string)
-			if sessionID == "" && !ok {
+			token, ok := session.Values["session_id"].(string)
+			if token == "" && !ok {
 				log.Println("redirect cause no sessionID from cookie")
 				return next(c)
 			}
 
-			_, err = query.GetUserSession(ctx, sessionID)
-			if err != nil {
+			sessionDat, err := sessions.Get(ctx, token)
+			if err != nil || !sessionDat.Active(time.Now()) {
 				log.Println("redirect cause no sessionID from DB")
 				return next(c)
 			}
 
-			userIDStr, ok := session.Values["user_id"].(string)
-			if !ok {
-				return c.String(
-					http.StatusInternalServerError,
-					"Internal Server Error, at debug_block_auth:1",
-				)
-			}
-
-			userID, err := uuid.Parse(userIDStr)
-			if err != nil {
-				return c.String(
-					http.StatusInternalServerError,
-					"Internal Server Error, at debug_block_auth:2",
-				)
-			}
-
-			roles, err := query.GetUserRolesByUserID(ctx, userID)
+			roles, err := config.Server.LoadUserRoles(ctx, sessionDat.UserID)
 			if err != nil {
 				return c.String(http.StatusInternalServerError, err.Error())
 			}
 
-			userRole := roles[0].Role
-
-			switch len(roles) {
-			case 1:
-				if userRole == utils.USER_ROLE_STUDENT || userRole == utils.USER_ROLE_TEACHER {
-					return c.Redirect(http.StatusFound, "/")
-				} else {
-					return c.Redirect(http.StatusFound, "/admin/panel")
-				}
-			case 2:
-				return c.Redirect(http.StatusFound, "/")
-			}
-
+			// already logged in, the home of the role the session acts as
+			activeRole, _ := session.Values[activeRoleKey].(string)
+			return c.Redirect(http.StatusFound, homeURL(server.DefaultActiveRole(roles, activeRole)))
 		}
 
 		// If request un-skipper endpoint goes right up here
@@ -100,14 +83,14 @@ func (config *webConfig) MiddlewareAuthN(next echo.HandlerFunc) echo.HandlerFunc
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
 
-		sessionID, ok := session.Values["session_id"].(string)
-		if !ok && sessionID == "" {
+		token, ok := session.Values["session_id"].(string)
+		if !ok && token == "" {
 			return c.Redirect(http.StatusFound, "/login")
 		}
 
-		sessionDat, err := query.GetUserSession(ctx, sessionID)
-		if err != nil || sessionDat.IsRevoked || time.Now().After(sessionDat.ExpireAt) {
-			query.DeleteUserSession(ctx, sessionID)
+		sessionDat, err := sessions.Get(ctx, token)
+		if err != nil || !sessionDat.Active(time.Now()) {
+			sessions.Delete(ctx, token)
 
 			session.Options.MaxAge = -1
 			if err := session.Save(c.Request(), c.Response()); err != nil {
@@ -118,12 +101,25 @@ func (config *webConfig) MiddlewareAuthN(next echo.HandlerFunc) echo.HandlerFunc
 		}
 
 		// update last_activity, everytime user make a request
-		if err := query.UpdateLastActivityUserSession(ctx, sessionID); err != nil {
+		if err := sessions.Touch(ctx, token); err != nil {
 			return c.String(http.StatusInternalServerError, err.Error())
 		}
 
 		c.Set("user_id", sessionDat.UserID)
 
+		activeRole, _ := session.Values[activeRoleKey].(string)
+		c.Set("active_role", activeRole)
+
+		// the admin asked for a new password, nothing else until it's changed
+		if required, _ := session.Values[passwordChangeKey].(bool); required && !slices.Contains(passwordChangeEndpoint, reqPath) {
+			return c.Redirect(http.StatusFound, "/account/password")
+		}
+
+		// an admin viewing the app as a student
+		if _, ok := session.Values[impersonatedUserKey]; ok {
+			return config.serveImpersonated(c, session, sessionDat.UserID, activeRole, next)
+		}
+
 		return next(c)
 	}
 }
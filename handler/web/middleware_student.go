@@ -1,9 +1,7 @@
 package web
 
 import (
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
@@ -11,12 +9,12 @@ import (
 
 type StudentData struct {
 	StudyPlan database.StudyPlan
-	Room      database.Room
 }
 
+// MiddlewareStudent resolves the first semester study plan of the major,
+// the room is assigned later inside the creation tx (server.AssignRoom)
 func (config *webConfig) MiddlewareStudent(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		var roomPrefix string
 		ctx := c.Request().Context()
 		qtx := config.Server.Queries
 
@@ -26,54 +24,12 @@ func (config *webConfig) MiddlewareStudent(next echo.HandlerFunc) echo.HandlerFu
 			Semester: int32(1),
 			Major:    major,
 		})
-
-		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
-		}
-
-		switch major {
-		case "TEKNIK INFROMATIKA":
-			roomPrefix = "TI"
-		case "REKAYASA PERANGKAT LUNAK":
-			roomPrefix = "RPL"
-		case "AKUNTANSI":
-			roomPrefix = "AK"
-		}
-
-		pattern := "%" + roomPrefix + "%"
-		var room database.Room
-
-		rooms, err := qtx.GetStudentRoom(ctx, pattern)
-		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
-		}
-
-		if len(rooms) == 0 {
-			c.String(http.StatusBadRequest, err.Error())
-		}
-
-		studentClassCount := major + "-StudentCount"
-		studentCount, err := qtx.GetCollectionMetaValue(ctx, studentClassCount)
 		if err != nil {
 			return c.String(http.StatusBadRequest, err.Error())
 		}
 
-		n, _ := strconv.Atoi(studentCount)
-		if n > 10 {
-			return c.String(
-				http.StatusBadRequest,
-				fmt.Sprintf("Class from %v is all full", major),
-			)
-		}
-		if n < 5 {
-			room = rooms[0]
-		}
-		if n >= 5 && n < 11 {
-			room = rooms[1]
-		}
-		c.Set("studentData", &StudentData{StudyPlan: studyPlan, Room: room})
+		c.Set("studentData", &StudentData{StudyPlan: studyPlan})
 
 		return next(c)
-
 	}
 }
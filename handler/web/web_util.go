@@ -9,24 +9,21 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/storage"
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/utils"
 )
 
-var (
-	MAJOR = []string{"TEKNIK INFORMATIKA", "REKAYASA PERANGKAT LUNAK", "AKUNTANSI"}
-	ROOM  = []string{"TIR1", "TIR2", "RPLR1", "RPLR2", "AKR1", "AKR2"}
-	YEAR  = time.Now().Year()
-)
-
 type Data = map[string]any
 
 type webConfig struct {
 	Server      *server.Server
+	Catalog     *utils.Catalog
 	sessionName string
 	store       *sessions.CookieStore
 }
@@ -90,6 +87,20 @@ func NewWebConfig() (*webConfig, error) {
 		return nil, err
 	}
 
+	blob, err := storage.NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	serverCfg.Storage = blob
+
+	provider, err := server.NewOIDCFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	serverCfg.OIDC = provider
+
 	sessionKey := os.Getenv("session_key")
 	if sessionKey == "" {
 		return nil, errors.New("cannot find the sessionKey")
@@ -107,6 +118,7 @@ func NewWebConfig() (*webConfig, error) {
 
 	return &webConfig{
 		Server:      serverCfg,
+		Catalog:     utils.NewCatalog(serverCfg.Queries),
 		sessionName: "web_session",
 		store:       store,
 	}, nil
@@ -120,3 +132,50 @@ func IsCacheValid(c echo.Context, lastModified time.Time) (bool, string) {
 	}
 	return false, ""
 }
+
+// truncate cuts s to at most n bytes without splitting a utf-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// describeUserAgent turns the user agent into "Browser on OS" for the
+// sessions page, the raw string stays available in the title attribute
+func describeUserAgent(ua string) string {
+	browser, os := "Unknown browser", "unknown device"
+
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	switch {
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	return browser + " on " + os
+}
@@ -10,16 +10,6 @@ import (
 	"time"
 )
 
-const addToFreelist = `-- name: AddToFreelist :exec
-INSERT INTO collection_meta (name, value)
-VALUES ('freelist-nim', $1)
-`
-
-func (q *Queries) AddToFreelist(ctx context.Context, value string) error {
-	_, err := q.db.ExecContext(ctx, addToFreelist, value)
-	return err
-}
-
 const decrementValueByName = `-- name: DecrementValueByName :exec
 UPDATE collection_meta
 SET value = (CAST(value as INTEGER)-1)::VARCHAR
@@ -31,16 +21,6 @@ func (q *Queries) DecrementValueByName(ctx context.Context, name string) error {
 	return err
 }
 
-const deleteFreelistNim = `-- name: DeleteFreelistNim :exec
-DELETE FROM collection_meta
-WHERE name = 'freelist-nim' AND value = $1
-`
-
-func (q *Queries) DeleteFreelistNim(ctx context.Context, value string) error {
-	_, err := q.db.ExecContext(ctx, deleteFreelistNim, value)
-	return err
-}
-
 const getCollectionMetaLastModified = `-- name: GetCollectionMetaLastModified :one
 SELECT updated_at FROM collection_meta
 WHERE name = $1
@@ -65,20 +45,6 @@ func (q *Queries) GetCollectionMetaValue(ctx context.Context, name string) (stri
 	return value, err
 }
 
-const getFreelistNim = `-- name: GetFreelistNim :one
-SELECT value FROM collection_meta
-WHERE name = 'freelist-nim'
-ORDER BY value ASC
-LIMIT 1
-`
-
-func (q *Queries) GetFreelistNim(ctx context.Context) (string, error) {
-	row := q.db.QueryRowContext(ctx, getFreelistNim)
-	var value string
-	err := row.Scan(&value)
-	return value, err
-}
-
 const incrementValueByname = `-- name: IncrementValueByname :exec
 UPDATE collection_meta
 SET value = (CAST(value as INTEGER)+1)::VARCHAR
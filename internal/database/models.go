@@ -5,11 +5,47 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpireAt   sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type ApiToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ApiKeyID  uuid.UUID
+	TokenHash string
+	ExpireAt  time.Time
+}
+
+type AuditEvent struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ActorID    uuid.NullUUID
+	ActorEmail string
+	ActorRole  string
+	Ip         string
+	Action     string
+	TargetType string
+	TargetID   string
+	Changes    json.RawMessage
+}
+
 type Classroom struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
@@ -26,21 +62,140 @@ type CollectionMetum struct {
 	Value     string
 }
 
+type Course struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Description string
+	CourseDate  time.Time
+	FileID      string
+	FileName    string
+	TeacherID   uuid.UUID
+	IsArchived  bool
+	FileStatus  string
+}
+
+type EmailVerification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+	ExpireAt  time.Time
+	UsedAt    sql.NullTime
+}
+
+type Enrollment struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	StudentID   uuid.UUID
+	CourseID    uuid.UUID
+	StudyPlanID uuid.UUID
+	Semester    int32
+	Status      string
+}
+
+type Job struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Kind        string
+	Payload     json.RawMessage
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       time.Time
+	LockedAt    sql.NullTime
+	LastError   string
+}
+
+type LoginLockout struct {
+	UserID       uuid.UUID
+	FailedCount  int32
+	LockoutCount int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type Major struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Code      string
+}
+
+type NimReleased struct {
+	Nim        string
+	Year       int32
+	MajorCode  string
+	ReleasedAt time.Time
+}
+
+type NimSequence struct {
+	Year       int32
+	MajorCode  string
+	LastSerial int32
+	UpdatedAt  time.Time
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+	ExpireAt  time.Time
+	UsedAt    sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+}
+
+type Role struct {
+	Name        string
+	CreatedAt   time.Time
+	Description string
+	IsSystem    bool
+}
+
+type RolePermission struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	Role       string
+	Permission string
+}
+
+type RolePolicy struct {
+	Role        string
+	UpdatedAt   time.Time
+	RequireTotp bool
+}
+
 type Room struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	Capacity  int32
+	Major     string
 }
 
 type Session struct {
 	ID           uuid.UUID
 	CreatedAt    time.Time
 	LastActivity time.Time
-	SessionID    string
+	TokenHash    string
 	UserID       uuid.UUID
 	IsRevoked    bool
 	ExpireAt     time.Time
+	UserAgent    string
+	IpAddress    string
 }
 
 type Student struct {
@@ -67,12 +222,47 @@ type StudyPlan struct {
 	Major     string
 }
 
+type StudyPlanCourse struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	StudyPlanID uuid.UUID
+	CourseID    uuid.UUID
+}
+
+type Teacher struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Nip         string
+	Name        string
+	Email       string
+	PhoneNumber string
+	DateOfBirth time.Time
+	Subjects    string
+	UserID      uuid.UUID
+}
+
 type User struct {
-	ID           uuid.UUID
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	Email        string
-	PasswordHash string
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Email                  string
+	PasswordHash           string
+	TotpSecret             string
+	TotpEnabled            bool
+	TotpLastStep           int64
+	PasswordChangeRequired bool
+	PasswordChangedAt      sql.NullTime
+	EmailVerifiedAt        sql.NullTime
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+	UserID      uuid.UUID
+	Issuer      string
+	Subject     string
 }
 
 type UserRole struct {
@@ -11,8 +11,120 @@ import (
 	"github.com/google/uuid"
 )
 
+const createRoom = `-- name: CreateRoom :one
+INSERT INTO rooms (name, capacity, major)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at, name, capacity, major
+`
+
+type CreateRoomParams struct {
+	Name     string
+	Capacity int32
+	Major    string
+}
+
+func (q *Queries) CreateRoom(ctx context.Context, arg CreateRoomParams) (Room, error) {
+	row := q.db.QueryRowContext(ctx, createRoom, arg.Name, arg.Capacity, arg.Major)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Capacity,
+		&i.Major,
+	)
+	return i, err
+}
+
+const deleteRoomById = `-- name: DeleteRoomById :exec
+DELETE FROM rooms
+WHERE id = $1
+`
+
+func (q *Queries) DeleteRoomById(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRoomById, id)
+	return err
+}
+
+const deleteRoomsByMajor = `-- name: DeleteRoomsByMajor :exec
+DELETE FROM rooms
+WHERE major = $1
+`
+
+func (q *Queries) DeleteRoomsByMajor(ctx context.Context, major string) error {
+	_, err := q.db.ExecContext(ctx, deleteRoomsByMajor, major)
+	return err
+}
+
+const getLeastFullRoomByMajor = `-- name: GetLeastFullRoomByMajor :one
+SELECT r.id, r.created_at, r.updated_at, r.name, r.capacity, r.major FROM rooms AS r
+WHERE r.major = $1
+        AND r.capacity > (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id)
+ORDER BY (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id), r.name
+LIMIT 1
+`
+
+func (q *Queries) GetLeastFullRoomByMajor(ctx context.Context, major string) (Room, error) {
+	row := q.db.QueryRowContext(ctx, getLeastFullRoomByMajor, major)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Capacity,
+		&i.Major,
+	)
+	return i, err
+}
+
+const getRoomAll = `-- name: GetRoomAll :many
+SELECT r.id, r.name, r.capacity, r.major,
+        (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id)::INT AS students_count
+FROM rooms AS r
+ORDER BY r.major, r.name
+`
+
+type GetRoomAllRow struct {
+	ID            uuid.UUID
+	Name          string
+	Capacity      int32
+	Major         string
+	StudentsCount int32
+}
+
+func (q *Queries) GetRoomAll(ctx context.Context) ([]GetRoomAllRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRoomAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomAllRow
+	for rows.Next() {
+		var i GetRoomAllRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Capacity,
+			&i.Major,
+			&i.StudentsCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStudentRoom = `-- name: GetStudentRoom :many
-SELECT id, created_at, updated_at, name FROM rooms
+SELECT id, created_at, updated_at, name, capacity, major FROM rooms
 WHERE name LIKE $1
 `
 
@@ -30,6 +142,8 @@ func (q *Queries) GetStudentRoom(ctx context.Context, name string) ([]Room, erro
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.Capacity,
+			&i.Major,
 		); err != nil {
 			return nil, err
 		}
@@ -45,7 +159,7 @@ func (q *Queries) GetStudentRoom(ctx context.Context, name string) ([]Room, erro
 }
 
 const getStudentRoomById = `-- name: GetStudentRoomById :one
-SELECT id, created_at, updated_at, name FROM rooms
+SELECT id, created_at, updated_at, name, capacity, major FROM rooms
 WHERE id = $1
 `
 
@@ -57,6 +171,61 @@ func (q *Queries) GetStudentRoomById(ctx context.Context, id uuid.UUID) (Room, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.Capacity,
+		&i.Major,
 	)
 	return i, err
 }
+
+const lockRoomsByMajor = `-- name: LockRoomsByMajor :many
+SELECT id, created_at, updated_at, name, capacity, major FROM rooms
+WHERE major = $1
+ORDER BY name
+FOR UPDATE
+`
+
+func (q *Queries) LockRoomsByMajor(ctx context.Context, major string) ([]Room, error) {
+	rows, err := q.db.QueryContext(ctx, lockRoomsByMajor, major)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Room
+	for rows.Next() {
+		var i Room
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Capacity,
+			&i.Major,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateRoomCapacity = `-- name: UpdateRoomCapacity :exec
+UPDATE rooms
+SET capacity = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateRoomCapacityParams struct {
+	ID       uuid.UUID
+	Capacity int32
+}
+
+func (q *Queries) UpdateRoomCapacity(ctx context.Context, arg UpdateRoomCapacityParams) error {
+	_, err := q.db.ExecContext(ctx, updateRoomCapacity, arg.ID, arg.Capacity)
+	return err
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const cleanupRevokedSessions = `-- name: CleanupRevokedSessions :exec
@@ -23,28 +24,38 @@ func (q *Queries) CleanupRevokedSessions(ctx context.Context) error {
 }
 
 const createUserSession = `-- name: CreateUserSession :one
-INSERT INTO sessions (session_id, user_id, expire_at)
-VALUES ($1, $2, $3)
-RETURNING id, created_at, last_activity, session_id, user_id, is_revoked, expire_at
+INSERT INTO sessions (token_hash, user_id, expire_at, user_agent, ip_address)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, last_activity, token_hash, user_id, is_revoked, expire_at, user_agent, ip_address
 `
 
 type CreateUserSessionParams struct {
-	SessionID string
+	TokenHash string
 	UserID    uuid.UUID
 	ExpireAt  time.Time
+	UserAgent string
+	IpAddress string
 }
 
 func (q *Queries) CreateUserSession(ctx context.Context, arg CreateUserSessionParams) (Session, error) {
-	row := q.db.QueryRowContext(ctx, createUserSession, arg.SessionID, arg.UserID, arg.ExpireAt)
+	row := q.db.QueryRowContext(ctx, createUserSession,
+		arg.TokenHash,
+		arg.UserID,
+		arg.ExpireAt,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i Session
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.LastActivity,
-		&i.SessionID,
+		&i.TokenHash,
 		&i.UserID,
 		&i.IsRevoked,
 		&i.ExpireAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
@@ -61,14 +72,53 @@ func (q *Queries) DeleteSessionByID(ctx context.Context, id uuid.UUID) error {
 
 const deleteUserSession = `-- name: DeleteUserSession :exec
 DELETE FROM sessions
-WHERE session_id = $1
+WHERE token_hash = $1
 `
 
-func (q *Queries) DeleteUserSession(ctx context.Context, sessionID string) error {
-	_, err := q.db.ExecContext(ctx, deleteUserSession, sessionID)
+func (q *Queries) DeleteUserSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSession, tokenHash)
 	return err
 }
 
+const getActiveSessionsByUserID = `-- name: GetActiveSessionsByUserID :many
+SELECT id, created_at, last_activity, token_hash, user_id, is_revoked, expire_at, user_agent, ip_address FROM sessions
+WHERE user_id = $1 AND is_revoked = FALSE AND expire_at > NOW()
+ORDER BY last_activity DESC
+`
+
+func (q *Queries) GetActiveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessionsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.LastActivity,
+			&i.TokenHash,
+			&i.UserID,
+			&i.IsRevoked,
+			&i.ExpireAt,
+			&i.UserAgent,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSessionIDAll = `-- name: GetSessionIDAll :many
 SELECT id, last_activity FROM sessions
 `
@@ -102,43 +152,118 @@ func (q *Queries) GetSessionIDAll(ctx context.Context) ([]GetSessionIDAllRow, er
 }
 
 const getUserSession = `-- name: GetUserSession :one
-SELECT id, created_at, last_activity, session_id, user_id, is_revoked, expire_at FROM sessions
-WHERE session_id = $1
+SELECT id, created_at, last_activity, token_hash, user_id, is_revoked, expire_at, user_agent, ip_address FROM sessions
+WHERE token_hash = $1
 `
 
-func (q *Queries) GetUserSession(ctx context.Context, sessionID string) (Session, error) {
-	row := q.db.QueryRowContext(ctx, getUserSession, sessionID)
+func (q *Queries) GetUserSession(ctx context.Context, tokenHash string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getUserSession, tokenHash)
 	var i Session
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.LastActivity,
-		&i.SessionID,
+		&i.TokenHash,
 		&i.UserID,
 		&i.IsRevoked,
 		&i.ExpireAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
 
+const revokeOtherUserSessions = `-- name: RevokeOtherUserSessions :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = $1 AND token_hash <> $2
+`
+
+type RevokeOtherUserSessionsParams struct {
+	UserID    uuid.UUID
+	TokenHash string
+}
+
+func (q *Queries) RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOtherUserSessions, arg.UserID, arg.TokenHash)
+	return err
+}
+
+const revokeUserSessionByID = `-- name: RevokeUserSessionByID :execrows
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE id = $1 AND user_id = $2
+`
+
+type RevokeUserSessionByIDParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeUserSessionByID(ctx context.Context, arg RevokeUserSessionByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSessionByID, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserSessionsByUserID = `-- name: RevokeUserSessionsByUserID :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = $1
+`
+
+func (q *Queries) RevokeUserSessionsByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserSessionsByUserID, userID)
+	return err
+}
+
+const revokeUserSessionsByUserIDs = `-- name: RevokeUserSessionsByUserIDs :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = ANY($1::UUID[])
+`
+
+func (q *Queries) RevokeUserSessionsByUserIDs(ctx context.Context, userIds []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserSessionsByUserIDs, pq.Array(userIds))
+	return err
+}
+
+const sweepUserSessions = `-- name: SweepUserSessions :exec
+DELETE FROM sessions
+WHERE is_revoked = TRUE OR expire_at < NOW()
+   OR last_activity < NOW() - $1::INT * INTERVAL '1 second'
+`
+
+func (q *Queries) SweepUserSessions(ctx context.Context, idleSeconds int32) error {
+	_, err := q.db.ExecContext(ctx, sweepUserSessions, idleSeconds)
+	return err
+}
+
 const updateLastActivityUserSession = `-- name: UpdateLastActivityUserSession :exec
 UPDATE sessions
 SET last_activity = NOW()
-WHERE session_id = $1
+WHERE token_hash = $1
 `
 
-func (q *Queries) UpdateLastActivityUserSession(ctx context.Context, sessionID string) error {
-	_, err := q.db.ExecContext(ctx, updateLastActivityUserSession, sessionID)
+func (q *Queries) UpdateLastActivityUserSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, updateLastActivityUserSession, tokenHash)
 	return err
 }
 
 const updateRevokeStatusUserSession = `-- name: UpdateRevokeStatusUserSession :exec
 UPDATE sessions
-SET is_revoked = $1
-WHERE session_id = $1
+SET is_revoked = $2
+WHERE token_hash = $1
 `
 
-func (q *Queries) UpdateRevokeStatusUserSession(ctx context.Context, isRevoked bool) error {
-	_, err := q.db.ExecContext(ctx, updateRevokeStatusUserSession, isRevoked)
+type UpdateRevokeStatusUserSessionParams struct {
+	TokenHash string
+	IsRevoked bool
+}
+
+func (q *Queries) UpdateRevokeStatusUserSession(ctx context.Context, arg UpdateRevokeStatusUserSessionParams) error {
+	_, err := q.db.ExecContext(ctx, updateRevokeStatusUserSession, arg.TokenHash, arg.IsRevoked)
 	return err
 }
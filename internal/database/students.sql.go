@@ -245,7 +245,7 @@ func (q *Queries) GetStudentByUserId(ctx context.Context, userID uuid.UUID) (Stu
 
 const getStudentsByRoomAndMajor = `-- name: GetStudentsByRoomAndMajor :many
 SELECT s.id, s.created_at, s.updated_at, s.name, s.email, s.nim, 
-s.phone_number, r.name as room, std.major 
+s.phone_number, r.name as room, std.major, s.user_id
 FROM students as s
 JOIN rooms as r
         ON s.room_id = r.id
@@ -269,6 +269,7 @@ type GetStudentsByRoomAndMajorRow struct {
 	PhoneNumber string
 	Room        string
 	Major       string
+	UserID      uuid.UUID
 }
 
 func (q *Queries) GetStudentsByRoomAndMajor(ctx context.Context, arg GetStudentsByRoomAndMajorParams) ([]GetStudentsByRoomAndMajorRow, error) {
@@ -290,6 +291,7 @@ func (q *Queries) GetStudentsByRoomAndMajor(ctx context.Context, arg GetStudents
 			&i.PhoneNumber,
 			&i.Room,
 			&i.Major,
+			&i.UserID,
 		); err != nil {
 			return nil, err
 		}
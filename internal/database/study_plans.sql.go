@@ -7,10 +7,112 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const addCourseToStudyPlan = `-- name: AddCourseToStudyPlan :exec
+INSERT INTO study_plan_courses (study_plan_id, course_id)
+VALUES ($1, $2)
+ON CONFLICT (study_plan_id, course_id) DO NOTHING
+`
+
+type AddCourseToStudyPlanParams struct {
+	StudyPlanID uuid.UUID
+	CourseID    uuid.UUID
+}
+
+func (q *Queries) AddCourseToStudyPlan(ctx context.Context, arg AddCourseToStudyPlanParams) error {
+	_, err := q.db.ExecContext(ctx, addCourseToStudyPlan, arg.StudyPlanID, arg.CourseID)
+	return err
+}
+
+const countStudyPlanCourse = `-- name: CountStudyPlanCourse :one
+SELECT COUNT(*) FROM study_plan_courses
+WHERE study_plan_id = $1 AND course_id = $2
+`
+
+type CountStudyPlanCourseParams struct {
+	StudyPlanID uuid.UUID
+	CourseID    uuid.UUID
+}
+
+func (q *Queries) CountStudyPlanCourse(ctx context.Context, arg CountStudyPlanCourseParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countStudyPlanCourse, arg.StudyPlanID, arg.CourseID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createStudyPlan = `-- name: CreateStudyPlan :exec
+INSERT INTO study_plans (semester, major)
+VALUES ($1, $2)
+`
+
+type CreateStudyPlanParams struct {
+	Semester int32
+	Major    string
+}
+
+func (q *Queries) CreateStudyPlan(ctx context.Context, arg CreateStudyPlanParams) error {
+	_, err := q.db.ExecContext(ctx, createStudyPlan, arg.Semester, arg.Major)
+	return err
+}
+
+const deleteStudyPlansByMajor = `-- name: DeleteStudyPlansByMajor :exec
+DELETE FROM study_plans
+WHERE major = $1
+`
+
+func (q *Queries) DeleteStudyPlansByMajor(ctx context.Context, major string) error {
+	_, err := q.db.ExecContext(ctx, deleteStudyPlansByMajor, major)
+	return err
+}
+
+const getCoursesByStudyPlanID = `-- name: GetCoursesByStudyPlanID :many
+SELECT c.id, c.created_at, c.updated_at, c.title, c.description, c.course_date, c.file_id, c.file_name, c.teacher_id, c.is_archived, c.file_status FROM courses AS c
+JOIN study_plan_courses AS spc
+        ON spc.course_id = c.id
+WHERE spc.study_plan_id = $1 AND c.is_archived = FALSE
+ORDER BY c.course_date DESC
+`
+
+func (q *Queries) GetCoursesByStudyPlanID(ctx context.Context, studyPlanID uuid.UUID) ([]Course, error) {
+	rows, err := q.db.QueryContext(ctx, getCoursesByStudyPlanID, studyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Course
+	for rows.Next() {
+		var i Course
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Description,
+			&i.CourseDate,
+			&i.FileID,
+			&i.FileName,
+			&i.TeacherID,
+			&i.IsArchived,
+			&i.FileStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStudyPlan = `-- name: GetStudyPlan :one
 SELECT id, created_at, updated_at, semester, major FROM study_plans
 WHERE semester = $1 AND major = $2
@@ -34,6 +136,40 @@ func (q *Queries) GetStudyPlan(ctx context.Context, arg GetStudyPlanParams) (Stu
 	return i, err
 }
 
+const getStudyPlanAll = `-- name: GetStudyPlanAll :many
+SELECT id, created_at, updated_at, semester, major FROM study_plans
+ORDER BY major, semester
+`
+
+func (q *Queries) GetStudyPlanAll(ctx context.Context) ([]StudyPlan, error) {
+	rows, err := q.db.QueryContext(ctx, getStudyPlanAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StudyPlan
+	for rows.Next() {
+		var i StudyPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Semester,
+			&i.Major,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStudyPlanById = `-- name: GetStudyPlanById :one
 SELECT id, created_at, updated_at, semester, major FROM study_plans
 WHERE id = $1
@@ -51,3 +187,137 @@ func (q *Queries) GetStudyPlanById(ctx context.Context, id uuid.UUID) (StudyPlan
 	)
 	return i, err
 }
+
+const getStudyPlanCourses = `-- name: GetStudyPlanCourses :many
+SELECT spc.study_plan_id, c.id, c.title, c.course_date, c.file_status, c.is_archived
+FROM study_plan_courses AS spc
+JOIN courses AS c
+        ON spc.course_id = c.id
+ORDER BY c.course_date DESC
+`
+
+type GetStudyPlanCoursesRow struct {
+	StudyPlanID uuid.UUID
+	ID          uuid.UUID
+	Title       string
+	CourseDate  time.Time
+	FileStatus  string
+	IsArchived  bool
+}
+
+func (q *Queries) GetStudyPlanCourses(ctx context.Context) ([]GetStudyPlanCoursesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStudyPlanCourses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStudyPlanCoursesRow
+	for rows.Next() {
+		var i GetStudyPlanCoursesRow
+		if err := rows.Scan(
+			&i.StudyPlanID,
+			&i.ID,
+			&i.Title,
+			&i.CourseDate,
+			&i.FileStatus,
+			&i.IsArchived,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStudyPlanStudentsCount = `-- name: GetStudyPlanStudentsCount :many
+SELECT study_plan_id, COUNT(*) AS count
+FROM students
+GROUP BY study_plan_id
+`
+
+type GetStudyPlanStudentsCountRow struct {
+	StudyPlanID uuid.UUID
+	Count       int64
+}
+
+func (q *Queries) GetStudyPlanStudentsCount(ctx context.Context) ([]GetStudyPlanStudentsCountRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStudyPlanStudentsCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStudyPlanStudentsCountRow
+	for rows.Next() {
+		var i GetStudyPlanStudentsCountRow
+		if err := rows.Scan(&i.StudyPlanID, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockStudyPlanById = `-- name: LockStudyPlanById :one
+SELECT id, created_at, updated_at, semester, major FROM study_plans
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockStudyPlanById(ctx context.Context, id uuid.UUID) (StudyPlan, error) {
+	row := q.db.QueryRowContext(ctx, lockStudyPlanById, id)
+	var i StudyPlan
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Semester,
+		&i.Major,
+	)
+	return i, err
+}
+
+const promoteStudentsStudyPlan = `-- name: PromoteStudentsStudyPlan :execrows
+UPDATE students
+SET study_plan_id = $1, updated_at = NOW()
+WHERE study_plan_id = $2
+`
+
+type PromoteStudentsStudyPlanParams struct {
+	NextStudyPlanID uuid.UUID
+	StudyPlanID     uuid.UUID
+}
+
+func (q *Queries) PromoteStudentsStudyPlan(ctx context.Context, arg PromoteStudentsStudyPlanParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, promoteStudentsStudyPlan, arg.NextStudyPlanID, arg.StudyPlanID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeCourseFromStudyPlan = `-- name: RemoveCourseFromStudyPlan :exec
+DELETE FROM study_plan_courses
+WHERE study_plan_id = $1 AND course_id = $2
+`
+
+type RemoveCourseFromStudyPlanParams struct {
+	StudyPlanID uuid.UUID
+	CourseID    uuid.UUID
+}
+
+func (q *Queries) RemoveCourseFromStudyPlan(ctx context.Context, arg RemoveCourseFromStudyPlanParams) error {
+	_, err := q.db.ExecContext(ctx, removeCourseFromStudyPlan, arg.StudyPlanID, arg.CourseID)
+	return err
+}
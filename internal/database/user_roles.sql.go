@@ -11,6 +11,18 @@ import (
 	"github.com/google/uuid"
 )
 
+const countUsersByRole = `-- name: CountUsersByRole :one
+SELECT COUNT(*) FROM user_roles
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersByRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersByRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUserRoles = `-- name: CreateUserRoles :one
 INSERT INTO user_roles (user_id, role)
 VALUES ($1, $2)
@@ -35,6 +47,21 @@ func (q *Queries) CreateUserRoles(ctx context.Context, arg CreateUserRolesParams
 	return i, err
 }
 
+const deleteUserRole = `-- name: DeleteUserRole :exec
+DELETE FROM user_roles
+WHERE user_id = $1 AND role = $2
+`
+
+type DeleteUserRoleParams struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+func (q *Queries) DeleteUserRole(ctx context.Context, arg DeleteUserRoleParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUserRole, arg.UserID, arg.Role)
+	return err
+}
+
 const getUserRolesByUserID = `-- name: GetUserRolesByUserID :many
 SELECT id, created_at, updated_at, user_id, role FROM user_roles
 WHERE user_id = $1
@@ -69,3 +96,12 @@ func (q *Queries) GetUserRolesByUserID(ctx context.Context, userID uuid.UUID) ([
 	}
 	return items, nil
 }
+
+const lockAdminBootstrap = `-- name: LockAdminBootstrap :exec
+SELECT pg_advisory_xact_lock(hashtext('admin_bootstrap'))
+`
+
+func (q *Queries) LockAdminBootstrap(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, lockAdminBootstrap)
+	return err
+}
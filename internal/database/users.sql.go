@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash)
 VALUES ($1, $2)
-RETURNING id, created_at, updated_at, email, password_hash
+RETURNING id, created_at, updated_at, email, password_hash, totp_secret, totp_enabled, totp_last_step, password_change_required, password_changed_at, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -32,6 +33,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.PasswordHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.PasswordChangeRequired,
+		&i.PasswordChangedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -46,8 +53,36 @@ func (q *Queries) DeleteUserByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = '', totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = TRUE, totp_last_step = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type EnableUserTOTPParams struct {
+	ID           uuid.UUID
+	TotpSecret   string
+	TotpLastStep int64
+}
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.TotpSecret, arg.TotpLastStep)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, password_hash FROM users
+SELECT id, created_at, updated_at, email, password_hash, totp_secret, totp_enabled, totp_last_step, password_change_required, password_changed_at, email_verified_at FROM users
 WHERE email = $1
 `
 
@@ -60,12 +95,18 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.PasswordHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.PasswordChangeRequired,
+		&i.PasswordChangedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, email, password_hash FROM users
+SELECT id, created_at, updated_at, email, password_hash, totp_secret, totp_enabled, totp_last_step, password_change_required, password_changed_at, email_verified_at FROM users
 WHERE id = $1
 `
 
@@ -78,12 +119,18 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.PasswordHash,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.PasswordChangeRequired,
+		&i.PasswordChangedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUsersAll = `-- name: GetUsersAll :many
-SELECT id, created_at, updated_at, email, password_hash FROM users
+SELECT id, created_at, updated_at, email, password_hash, totp_secret, totp_enabled, totp_last_step, password_change_required, password_changed_at, email_verified_at FROM users
 `
 
 func (q *Queries) GetUsersAll(ctx context.Context) ([]User, error) {
@@ -101,6 +148,12 @@ func (q *Queries) GetUsersAll(ctx context.Context) ([]User, error) {
 			&i.UpdatedAt,
 			&i.Email,
 			&i.PasswordHash,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.TotpLastStep,
+			&i.PasswordChangeRequired,
+			&i.PasswordChangedAt,
+			&i.EmailVerifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -116,17 +169,18 @@ func (q *Queries) GetUsersAll(ctx context.Context) ([]User, error) {
 }
 
 const getUsersAllJoinRoles = `-- name: GetUsersAllJoinRoles :many
-SELECT u.id, u.email, r.role, u.created_at
+SELECT u.id, u.email, r.role, u.created_at, u.email_verified_at
 FROM users AS u
 JOIN user_roles as r
   ON u.id = r.user_id
 `
 
 type GetUsersAllJoinRolesRow struct {
-	ID        uuid.UUID
-	Email     string
-	Role      string
-	CreatedAt time.Time
+	ID              uuid.UUID
+	Email           string
+	Role            string
+	CreatedAt       time.Time
+	EmailVerifiedAt sql.NullTime
 }
 
 func (q *Queries) GetUsersAllJoinRoles(ctx context.Context) ([]GetUsersAllJoinRolesRow, error) {
@@ -143,6 +197,7 @@ func (q *Queries) GetUsersAllJoinRoles(ctx context.Context) ([]GetUsersAllJoinRo
 			&i.Email,
 			&i.Role,
 			&i.CreatedAt,
+			&i.EmailVerifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -156,3 +211,80 @@ func (q *Queries) GetUsersAllJoinRoles(ctx context.Context) ([]GetUsersAllJoinRo
 	}
 	return items, nil
 }
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markUserEmailVerified, id)
+	return err
+}
+
+const requireUserPasswordChange = `-- name: RequireUserPasswordChange :execrows
+UPDATE users
+SET password_change_required = TRUE, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RequireUserPasswordChange(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requireUserPasswordChange, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, password_change_required = FALSE, password_changed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const updateUserPasswordHash = `-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET password_hash = $1
+WHERE id = $2 AND password_hash = $3
+`
+
+type UpdateUserPasswordHashParams struct {
+	PasswordHash string
+	ID           uuid.UUID
+	VerifiedHash string
+}
+
+func (q *Queries) UpdateUserPasswordHash(ctx context.Context, arg UpdateUserPasswordHashParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPasswordHash, arg.PasswordHash, arg.ID, arg.VerifiedHash)
+	return err
+}
+
+const updateUserTOTPStep = `-- name: UpdateUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2
+`
+
+type UpdateUserTOTPStepParams struct {
+	ID           uuid.UUID
+	TotpLastStep int64
+}
+
+func (q *Queries) UpdateUserTOTPStep(ctx context.Context, arg UpdateUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
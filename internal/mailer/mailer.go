@@ -0,0 +1,63 @@
+// Package mailer delivers the outgoing emails (password reset, verification).
+// The driver comes from the environment, the outbox driver writes the
+// messages as files so the flow can be tested without a mail server
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Validate refuses the header injection, the address & subject go
+// straight into the message headers
+func (m Message) Validate() error {
+	if m.To == "" {
+		return errors.New("mailer: message has no recipient")
+	}
+
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("mailer: header contains a line break")
+	}
+
+	return nil
+}
+
+// NewFromEnv picks the driver from "mailer_driver" (outbox|smtp)
+func NewFromEnv() (Mailer, error) {
+	from := os.Getenv("mail_from")
+	if from == "" {
+		from = "no-reply@rambanbelajar.local"
+	}
+
+	switch driver := os.Getenv("mailer_driver"); driver {
+	case "", "outbox":
+		dir := os.Getenv("mailer_outbox_path")
+		if dir == "" {
+			dir = "outbox"
+		}
+		return NewOutbox(dir, from)
+	case "smtp":
+		return NewSMTP(SMTPConfig{
+			Host:     os.Getenv("smtp_host"),
+			Port:     os.Getenv("smtp_port"),
+			Username: os.Getenv("smtp_username"),
+			Password: os.Getenv("smtp_password"),
+			From:     from,
+		})
+	default:
+		return nil, fmt.Errorf("error: unknown mailer_driver %q", driver)
+	}
+}
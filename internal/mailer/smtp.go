@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTP struct {
+	cfg SMTPConfig
+}
+
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("error: cannot find smtp_host in the environment")
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+
+	return &SMTP{cfg: cfg}, nil
+}
+
+// Send goes through net/smtp, it upgrades to STARTTLS when the server
+// offers it (PlainAuth refuses to send the password in the clear)
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(
+			net.JoinHostPort(s.cfg.Host, s.cfg.Port),
+			auth,
+			s.cfg.From,
+			[]string{msg.To},
+			compose(s.cfg.From, msg),
+		)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up      apply all the pending migrations
+  down    roll back the latest applied migration
+  status  list the migrations and when they were applied`
+
+// Command is the "migrate" subcommand of the binaries, args is what comes
+// after the "migrate" word
+func Command(ctx context.Context, db *sql.DB, fsys fs.FS, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	runner, err := New(db, fsys)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := runner.Up(ctx)
+		for _, m := range done {
+			fmt.Fprintf(w, "OK   %s\n", m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(w, "no migrations to run, the database is up to date")
+		}
+		return nil
+
+	case "down":
+		m, err := runner.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "OK   %s (rolled back)\n", m.Name)
+		return nil
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%-24s  %s\n", "Applied At", "Migration")
+		fmt.Fprintf(w, "%-24s  %s\n", "==========", "=========")
+		for _, s := range statuses {
+			appliedAt := "Pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%-24s  %s\n", appliedAt, s.Name)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
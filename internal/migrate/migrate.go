@@ -0,0 +1,337 @@
+// Package migrate applies the goose annotated schema files embedded in the
+// binaries. The applied versions are kept in goose_db_version (same layout
+// as the goose cli), so the database migrated by hand stays in sync
+package migrate
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BASE_VERSION is predefined_tables.sql, it has no number in the name.
+	// goose inserts version 0 when it creates goose_db_version, so on the
+	// database set up by the goose cli the base tables count as applied
+	BASE_VERSION   = 0
+	BASE_FILE_NAME = "predefined_tables.sql"
+
+	// any constant, only has to be the same between the binaries
+	advisoryLockID = 48172024
+
+	annotationPrefix = "-- +goose"
+)
+
+var ErrNothingToRollback = errors.New("migrate: there is no applied migration to roll back")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	NoTx    bool
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Load collects every .sql file of the fs (any depth), ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	var migrations []Migration
+	seen := map[int64]string{}
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".sql" {
+			return nil
+		}
+
+		text, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		m, err := parse(path.Base(p), string(text))
+		if err != nil {
+			return err
+		}
+
+		if other, ok := seen[m.Version]; ok {
+			return fmt.Errorf("migrate: version %d is used by both %s and %s", m.Version, other, p)
+		}
+		seen[m.Version] = p
+
+		migrations = append(migrations, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+func parse(name, text string) (Migration, error) {
+	m := Migration{Name: name}
+
+	if name == BASE_FILE_NAME {
+		m.Version = BASE_VERSION
+	} else {
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version <= BASE_VERSION {
+			return m, fmt.Errorf("migrate: %s has no version prefix (NNN_name.sql)", name)
+		}
+		m.Version = version
+	}
+
+	var up, down strings.Builder
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if annotation, ok := strings.CutPrefix(strings.TrimSpace(line), annotationPrefix); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				current = &up
+			case "Down":
+				current = &down
+			case "NO TRANSACTION":
+				m.NoTx = true
+			case "StatementBegin", "StatementEnd":
+				// the whole section goes in one exec, nothing to split
+			default:
+				return m, fmt.Errorf("migrate: %s has unknown annotation %q", name, line)
+			}
+			continue
+		}
+
+		if current != nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return m, err
+	}
+
+	m.Up = strings.TrimSpace(up.String())
+	m.Down = strings.TrimSpace(down.String())
+
+	if m.Up == "" {
+		return m, fmt.Errorf("migrate: %s has no %s Up section", name, annotationPrefix)
+	}
+
+	return m, nil
+}
+
+// Up applies every pending migration in order, each one in its own tx
+// (unless annotated NO TRANSACTION). It returns the applied ones
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+
+			if err := run(ctx, conn, m, m.Up,
+				`INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)`,
+			); err != nil {
+				return fmt.Errorf("migrate: %s up: %w", m.Name, err)
+			}
+
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back the latest applied migration
+func (r *Runner) Down(ctx context.Context) (Migration, error) {
+	var done Migration
+
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range slices.Backward(r.migrations) {
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+
+			if err := run(ctx, conn, m, m.Down,
+				`DELETE FROM goose_db_version WHERE version_id = $1`,
+			); err != nil {
+				return fmt.Errorf("migrate: %s down: %w", m.Name, err)
+			}
+
+			done = m
+			return nil
+		}
+
+		return ErrNothingToRollback
+	})
+
+	return done, err
+}
+
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := r.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			appliedAt, ok := applied[m.Version]
+			statuses = append(statuses, Status{
+				Migration: m,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs fn on one connection holding the advisory lock, so the
+// webserver & apiserver never migrate at the same time
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS goose_db_version (
+			id SERIAL PRIMARY KEY,
+			version_id BIGINT NOT NULL,
+			is_applied BOOLEAN NOT NULL,
+			tstamp TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+	); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions follows goose, the latest row of the version wins
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version_id, is_applied, tstamp FROM goose_db_version
+		ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime // goose cli leaves it nullable
+
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+
+		if isApplied {
+			applied[version] = tstamp.Time
+		} else {
+			delete(applied, version)
+		}
+	}
+
+	return applied, rows.Err()
+}
+
+func run(ctx context.Context, conn *sql.Conn, m Migration, statements, record string) error {
+	if m.NoTx {
+		if statements != "" {
+			if _, err := conn.ExecContext(ctx, statements); err != nil {
+				return err
+			}
+		}
+		_, err := conn.ExecContext(ctx, record, m.Version)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the section has no parameters, lib/pq sends it as a simple query
+	// so the multiple statements go in one round trip
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, record, m.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
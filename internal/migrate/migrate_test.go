@@ -0,0 +1,293 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/sql/schema"
+)
+
+var testMigrations = fstest.MapFS{
+	"001_widgets.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE widgets (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE widgets;
+`)},
+	"002_widgets_index.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY widgets_name_idx ON widgets (name);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY widgets_name_idx;
+`)},
+	"003_gadgets.sql": {Data: []byte(`-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE gadgets (id SERIAL PRIMARY KEY);
+INSERT INTO gadgets DEFAULT VALUES;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE gadgets;
+`)},
+}
+
+// testDB is the postgres of db_url with the search_path on a schema of
+// the test alone, so goose_db_version & the tables of the app are left
+// alone. Without db_url the test is skipped
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbURL := os.Getenv("db_url")
+	if dbURL == "" {
+		t.Skip("db_url is not set, skipping the postgres test")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := "migrate_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	if _, err := admin.Exec("CREATE SCHEMA " + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + name + " CASCADE"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// lib/pq sends the unknown connection parameters as run-time settings
+	switch {
+	case strings.Contains(dbURL, "://") && strings.Contains(dbURL, "?"):
+		dbURL += "&search_path=" + name
+	case strings.Contains(dbURL, "://"):
+		dbURL += "?search_path=" + name
+	default:
+		dbURL += " search_path=" + name
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// versionRows is goose_db_version as (version, is_applied) in insert order
+func versionRows(t *testing.T, db *sql.DB) [][2]any {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT version_id, is_applied FROM goose_db_version ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var result [][2]any
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, [2]any{version, applied})
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+
+	var name sql.NullString
+	if err := db.QueryRow(`SELECT to_regclass($1)::TEXT`, table).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	return name.Valid
+}
+
+func TestLoadSchema(t *testing.T) {
+	migrations, err := Load(schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migrations[0].Version != BASE_VERSION || migrations[0].Name != BASE_FILE_NAME {
+		t.Errorf("first migration is %v (%d), want the base tables", migrations[0].Name, migrations[0].Version)
+	}
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("%v is out of order", migrations[i].Name)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for name, text := range map[string]string{
+		"widgets.sql":     "-- +goose Up\nSELECT 1;",
+		"001_noup.sql":    "-- +goose Down\nSELECT 1;",
+		"002_unknown.sql": "-- +goose Up\n-- +goose Sideways\nSELECT 1;",
+	} {
+		if _, err := parse(name, text); err == nil {
+			t.Errorf("%v: parse accepted it", name)
+		}
+	}
+}
+
+func TestUpStatusDownUp(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	runner, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := runner.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 3 {
+		t.Fatalf("up applied %d migrations, want 3", len(done))
+	}
+
+	if !tableExists(t, db, "widgets") || !tableExists(t, db, "gadgets") || !tableExists(t, db, "widgets_name_idx") {
+		t.Fatal("up didn't create the tables & the index")
+	}
+
+	// the second up has nothing to do
+	if done, err := runner.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second up: applied %d, err %v", len(done), err)
+	}
+
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.AppliedAt.IsZero() {
+			t.Errorf("status of %v: applied %v at %v", s.Name, s.Applied, s.AppliedAt)
+		}
+	}
+
+	m, err := runner.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 3 || tableExists(t, db, "gadgets") {
+		t.Fatalf("down rolled back %v, gadgets still there: %v", m.Name, tableExists(t, db, "gadgets"))
+	}
+
+	statuses, err = runner.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[2].Applied || !statuses[1].Applied {
+		t.Errorf("status after down: %+v", statuses)
+	}
+
+	done, err = runner.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].Version != 3 || !tableExists(t, db, "gadgets") {
+		t.Fatalf("up after down applied %v", done)
+	}
+
+	// down deletes the row of the version, the second up adds it again
+	want := [][2]any{{int64(1), true}, {int64(2), true}, {int64(3), true}}
+	got := versionRows(t, db)
+	if len(got) != len(want) {
+		t.Fatalf("goose_db_version is %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("goose_db_version row %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// down to nothing, then there is nothing left to roll back
+	for range 3 {
+		if _, err := runner.Down(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runner.Down(ctx); !errors.Is(err, ErrNothingToRollback) {
+		t.Errorf("down on the empty database: %v, want ErrNothingToRollback", err)
+	}
+}
+
+func TestUpWaitsForTheLock(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	runner, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the other binary migrating at the same time
+	holder, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockID); err != nil {
+		t.Fatal(err)
+	}
+
+	finished := make(chan error, 1)
+	go func() {
+		_, err := runner.Up(ctx)
+		finished <- err
+	}()
+
+	select {
+	case err := <-finished:
+		t.Fatalf("up ran while the lock was held, err %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockID); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("up didn't run after the lock was released")
+	}
+
+	// the runner gives the lock back, it can be taken right away
+	var locked bool
+	if err := holder.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, advisoryLockID).Scan(&locked); err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Error("the runner kept the advisory lock")
+	}
+	holder.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockID)
+}
@@ -0,0 +1,277 @@
+// Package oidc is the relying party side of OpenID Connect: the
+// authorization code flow with PKCE, the id token checked against the
+// provider's published keys. Only what the single sign-on login needs,
+// any provider with the discovery document works (Google, Keycloak, ...)
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	httpTimeout   = 10 * time.Second
+	// the discovery & keys are fetched again after this, the providers
+	// rotate the signing keys from time to time
+	metadataTTL = time.Hour
+)
+
+var ErrDisabled = errors.New("oidc: single sign-on is not configured")
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim is the claim with the groups, dot separated for the
+	// nested one (keycloak: "realm_access.roles")
+	GroupsClaim string
+	// RoleMap maps the group of the provider onto the app role
+	RoleMap map[string]string
+}
+
+// ConfigFromEnv reads oidc_issuer, oidc_client_id, oidc_client_secret,
+// oidc_redirect_url, oidc_groups_claim & oidc_role_map
+// ("teachers=teacher,staff=admin"). ErrDisabled without the issuer
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Issuer:       strings.TrimRight(os.Getenv("oidc_issuer"), "/"),
+		ClientID:     os.Getenv("oidc_client_id"),
+		ClientSecret: os.Getenv("oidc_client_secret"),
+		RedirectURL:  os.Getenv("oidc_redirect_url"),
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  os.Getenv("oidc_groups_claim"),
+		RoleMap:      map[string]string{},
+	}
+
+	if cfg.Issuer == "" {
+		return cfg, ErrDisabled
+	}
+
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return cfg, errors.New("oidc: oidc_client_id & oidc_redirect_url are required with oidc_issuer")
+	}
+
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	for _, pair := range strings.Split(os.Getenv("oidc_role_map"), ",") {
+		group, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		cfg.RoleMap[strings.TrimSpace(group)] = strings.TrimSpace(role)
+	}
+
+	return cfg, nil
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	meta      *metadata
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+func NewProvider(cfg Config) *Provider {
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (p *Provider) Config() Config {
+	return p.cfg
+}
+
+// AuthRequest is kept by the app between the redirect and the callback
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func NewAuthRequest() (AuthRequest, error) {
+	var r AuthRequest
+	var err error
+
+	if r.State, err = randomString(); err != nil {
+		return r, err
+	}
+	if r.Nonce, err = randomString(); err != nil {
+		return r, err
+	}
+	if r.Verifier, err = randomString(); err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// AuthURL is where the browser goes to sign in, with the S256 challenge
+// of the verifier
+func (p *Provider) AuthURL(ctx context.Context, r AuthRequest) (string, error) {
+	meta, _, err := p.load(ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(r.Verifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", r.State)
+	q.Set("nonce", r.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades the code for the tokens & returns the verified claims
+// of the id token
+func (p *Provider) Exchange(ctx context.Context, code string, r AuthRequest) (*Claims, error) {
+	meta, _, err := p.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", r.Verifier)
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint answered %d: %s", resp.StatusCode, body)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("oidc: the token response has no id_token")
+	}
+
+	return p.verify(ctx, tokens.IDToken, r.Nonce)
+}
+
+// load returns the discovery document & the keys, refetched when stale
+// or when asked (the unknown key id)
+func (p *Provider) load(ctx context.Context, refresh bool) (*metadata, map[string]any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil && !refresh && time.Since(p.fetchedAt) < metadataTTL {
+		return p.meta, p.keys, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, p.cfg.Issuer+discoveryPath, &meta); err != nil {
+		return nil, nil, err
+	}
+
+	if strings.TrimRight(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, nil, fmt.Errorf("oidc: the discovery issuer %q is not %q", meta.Issuer, p.cfg.Issuer)
+	}
+
+	var set jwkSet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := set.publicKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.meta, p.keys, p.fetchedAt = &meta, keys, time.Now()
+	return p.meta, p.keys, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s answered %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,270 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "rambanbelajar"
+	testKeyID    = "test-key"
+	testNonce    = "test-nonce"
+)
+
+// testProvider is the httptest provider with the discovery, the key set
+// of the single RSA key & the token endpoint answering idToken
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// keyFetches counts the GETs of the key set
+	keyFetches atomic.Int32
+	// idToken is what the token endpoint hands out, the form is checked
+	// against verifier first
+	idToken  string
+	verifier string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tp := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{
+			Issuer:                tp.server.URL,
+			AuthorizationEndpoint: tp.server.URL + "/authorize",
+			TokenEndpoint:         tp.server.URL + "/token",
+			JWKSURI:               tp.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		tp.keyFetches.Add(1)
+		json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: testKeyID,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "authorization_code" || r.FormValue("code") != "test-code" ||
+			r.FormValue("client_id") != testClientID || r.FormValue("code_verifier") != tp.verifier {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"id_token":     tp.idToken,
+		})
+	})
+
+	tp.server = httptest.NewServer(mux)
+	t.Cleanup(tp.server.Close)
+
+	return tp
+}
+
+func (tp *testProvider) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:       tp.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost/auth/sso/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  "groups",
+		RoleMap:      map[string]string{"staff": "admin"},
+	})
+}
+
+// claims is the payload of the token the provider would sign for the
+// test client
+func (tp *testProvider) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":            tp.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "user-1@test.local",
+		"email_verified": true,
+		"name":           "User One",
+		"groups":         []string{"staff", "other"},
+		"nonce":          testNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign is the RS256 token of the claims with the key of the provider,
+// or with key when it's set
+func (tp *testProvider) sign(t *testing.T, kid string, claims map[string]any, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	if key == nil {
+		key = tp.key
+	}
+
+	signed := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) +
+		"." + encodeSegment(t, claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	ctx := context.Background()
+	tp := newTestProvider(t)
+	p := tp.provider()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(key string, value any) map[string]any {
+		claims := tp.claims()
+		claims[key] = value
+		return claims
+	}
+
+	// the header & payload without a signature, the ones that aren't RS256
+	unsigned := func(alg string) string {
+		return encodeSegment(t, map[string]string{"alg": alg, "typ": "JWT", "kid": testKeyID}) +
+			"." + encodeSegment(t, tp.claims())
+	}
+
+	// HS256 keyed with the client secret, the secret the app knows
+	hs256 := func() string {
+		signed := unsigned("HS256")
+		mac := hmac.New(sha256.New, []byte("test-secret"))
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	for _, tc := range []struct {
+		name  string
+		token string
+	}{
+		{"wrong issuer", tp.sign(t, testKeyID, with("iss", "https://evil.test.local"), nil)},
+		{"no issuer", tp.sign(t, testKeyID, with("iss", nil), nil)},
+		{"wrong audience", tp.sign(t, testKeyID, with("aud", "other-client"), nil)},
+		{"audience list without us", tp.sign(t, testKeyID, with("aud", []string{"a", "b"}), nil)},
+		{"expired", tp.sign(t, testKeyID, with("exp", time.Now().Add(-clockSkew-time.Minute).Unix()), nil)},
+		{"no expiry", tp.sign(t, testKeyID, with("exp", nil), nil)},
+		{"issued in the future", tp.sign(t, testKeyID, with("iat", time.Now().Add(clockSkew+time.Minute).Unix()), nil)},
+		{"nonce mismatch", tp.sign(t, testKeyID, with("nonce", "other-nonce"), nil)},
+		{"no nonce", tp.sign(t, testKeyID, with("nonce", nil), nil)},
+		{"no subject", tp.sign(t, testKeyID, with("sub", ""), nil)},
+		{"alg none", unsigned("none") + "."},
+		{"alg HS256", hs256()},
+		{"unknown kid", tp.sign(t, "rotated-away", tp.claims(), nil)},
+		{"signed by another key", tp.sign(t, testKeyID, tp.claims(), otherKey)},
+		{"not a jwt", "not.a-jwt"},
+	} {
+		claims, err := p.verify(ctx, tc.token, testNonce)
+		if !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%v: verify is %+v, %v, want ErrInvalidIDToken", tc.name, claims, err)
+		}
+	}
+}
+
+func TestVerifyUnknownKidRefetches(t *testing.T) {
+	ctx := context.Background()
+	tp := newTestProvider(t)
+	p := tp.provider()
+
+	if _, err := p.verify(ctx, tp.sign(t, testKeyID, tp.claims(), nil), testNonce); err != nil {
+		t.Fatal(err)
+	}
+	if n := tp.keyFetches.Load(); n != 1 {
+		t.Fatalf("%d key fetches for the known kid, want 1", n)
+	}
+
+	// the unknown key id is fetched again once, then refused
+	if _, err := p.verify(ctx, tp.sign(t, "rotated-away", tp.claims(), nil), testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("verify with the unknown kid: %v", err)
+	}
+	if n := tp.keyFetches.Load(); n != 2 {
+		t.Errorf("%d key fetches after the unknown kid, want 2", n)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	tp := newTestProvider(t)
+	p := tp.provider()
+
+	r, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Nonce = testNonce
+
+	tp.verifier = r.Verifier
+	tp.idToken = tp.sign(t, testKeyID, tp.claims(), nil)
+
+	claims, err := p.Exchange(ctx, "test-code", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "user-1" || claims.Email != "user-1@test.local" || !claims.EmailVerified ||
+		claims.Name != "User One" || !slices.Equal(claims.Groups, []string{"staff", "other"}) {
+		t.Errorf("claims are %+v", claims)
+	}
+	if roles := p.Roles(claims.Groups); !slices.Equal(roles, []string{"admin"}) {
+		t.Errorf("roles are %v, want [admin]", roles)
+	}
+
+	// the verifier of another request is refused by the provider (PKCE)
+	other, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Nonce = testNonce
+	if _, err := p.Exchange(ctx, "test-code", other); err == nil {
+		t.Error("the exchange with another verifier passed")
+	}
+
+	// the token of another login is refused by its nonce
+	r.Nonce = "other-nonce"
+	if _, err := p.Exchange(ctx, "test-code", r); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("exchange with another nonce: %v, want ErrInvalidIDToken", err)
+	}
+}
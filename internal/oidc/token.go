@@ -0,0 +1,303 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// the clock of the provider & ours don't agree to the second
+const clockSkew = time.Minute
+
+var ErrInvalidIDToken = errors.New("oidc: invalid id token")
+
+// Claims is the part of the id token the login uses
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Groups is nil when the token has no groups claim at all, an empty
+	// slice when it has one without groups
+	Groups []string
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// publicKeys keeps the RSA & P-256 signing keys, by key id
+func (s jwkSet) publicKeys() (map[string]any, error) {
+	keys := map[string]any{}
+
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, err
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, err
+			}
+			y, err := base64.RawURLEncoding.DecodeString(k.Y)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("oidc: the provider publishes no usable signing key")
+	}
+
+	return keys, nil
+}
+
+// verify checks the signature, issuer, audience, expiry & nonce of the
+// id token before anything in it is trusted
+func (p *Provider) verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	_, keys, err := p.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := pickKey(keys, header.Kid)
+	if !ok {
+		// the provider rotated its keys since the last fetch
+		if _, keys, err = p.load(ctx, true); err != nil {
+			return nil, err
+		}
+		if key, ok = pickKey(keys, header.Kid); !ok {
+			return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidIDToken, header.Kid)
+		}
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch header.Alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidIDToken)
+		}
+	default:
+		// "none" & the HMAC ones never pass, the client secret isn't a
+		// signing key here
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrInvalidIDToken, header.Alg)
+	}
+
+	var payload map[string]any
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iss, _ := payload["iss"].(string); strings.TrimRight(iss, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer", ErrInvalidIDToken)
+	}
+
+	if !hasAudience(payload["aud"], p.cfg.ClientID) {
+		return nil, fmt.Errorf("%w: audience", ErrInvalidIDToken)
+	}
+
+	now := time.Now()
+	exp, ok := payload["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+
+	if iat, ok := payload["iat"].(float64); ok && time.Unix(int64(iat), 0).After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+
+	if n, _ := payload["nonce"].(string); n == "" || n != nonce {
+		return nil, fmt.Errorf("%w: nonce", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{}
+	claims.Subject, _ = payload["sub"].(string)
+	claims.Email, _ = payload["email"].(string)
+	claims.Name, _ = payload["name"].(string)
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: subject", ErrInvalidIDToken)
+	}
+
+	// some providers send the flag as the string
+	switch v := payload["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string:
+		claims.EmailVerified = v == "true"
+	}
+
+	if groups, ok := lookupPath(payload, p.cfg.GroupsClaim); ok {
+		claims.Groups = []string{}
+		switch v := groups.(type) {
+		case []any:
+			for _, g := range v {
+				if s, ok := g.(string); ok {
+					claims.Groups = append(claims.Groups, s)
+				}
+			}
+		case string:
+			claims.Groups = append(claims.Groups, v)
+		}
+	}
+
+	return claims, nil
+}
+
+// Roles maps the groups onto the app roles with the role map, each
+// role once
+func (p *Provider) Roles(groups []string) []string {
+	var roles []string
+	for _, g := range groups {
+		role, ok := p.cfg.RoleMap[g]
+		if !ok {
+			continue
+		}
+		seen := false
+		for _, r := range roles {
+			seen = seen || r == role
+		}
+		if !seen {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// MappedRoles is every role the role map can hand out, the only roles the
+// login is allowed to add or take away
+func (p *Provider) MappedRoles() []string {
+	return p.Roles(mapKeys(p.cfg.RoleMap))
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func pickKey(keys map[string]any, kid string) (any, bool) {
+	if kid != "" {
+		key, ok := keys[kid]
+		return key, ok
+	}
+	// without the key id only the single key set is unambiguous
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func hasAudience(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func lookupPath(payload map[string]any, path string) (any, bool) {
+	var cur any = payload
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
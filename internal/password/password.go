@@ -0,0 +1,108 @@
+// Package password hashes & verifies the login passwords. Every hash is
+// stored with its algorithm & parameters ("$argon2id$v=19$m=...", "$2a$14$..."),
+// so the hashes written by the older settings keep verifying and are
+// rewritten with the current ones on the next login
+package password
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var ErrUnknownFormat = errors.New("password: unknown hash format")
+
+// Hasher is one algorithm with its parameters
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Handles reports whether the encoded hash is of this algorithm
+	Handles(encoded string) bool
+	Verify(password, encoded string) (bool, error)
+	// Outdated reports whether the encoded hash (of this algorithm) was
+	// written with other parameters than the hasher's
+	Outdated(encoded string) bool
+}
+
+// Manager hashes with the current hasher, and verifies with whichever of
+// the hashers wrote the hash
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+}
+
+func NewManager(current Hasher, legacy ...Hasher) *Manager {
+	return &Manager{
+		current: current,
+		hashers: append([]Hasher{current}, legacy...),
+	}
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+// Verify checks the password, rehash is true when the hash should be
+// replaced by the current hasher's (another algorithm or parameters)
+func (m *Manager) Verify(password, encoded string) (ok, rehash bool, err error) {
+	for i, hasher := range m.hashers {
+		if !hasher.Handles(encoded) {
+			continue
+		}
+
+		ok, err = hasher.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, i > 0 || hasher.Outdated(encoded), nil
+	}
+
+	return false, false, ErrUnknownFormat
+}
+
+// NewFromEnv reads password_hasher: argon2id (default) or bcrypt, with
+// argon2_memory (KiB), argon2_iterations, argon2_parallelism & bcrypt_cost.
+// The other algorithm still verifies, its hashes are upgraded on login
+func NewFromEnv() (*Manager, error) {
+	argon := DefaultArgon2id
+	bcrypt := DefaultBcrypt
+
+	for _, param := range []struct {
+		env string
+		set func(n int)
+	}{
+		{"argon2_memory", func(n int) { argon.Memory = uint32(n) }},
+		{"argon2_iterations", func(n int) { argon.Iterations = uint32(n) }},
+		{"argon2_parallelism", func(n int) { argon.Parallelism = uint8(n) }},
+		{"bcrypt_cost", func(n int) { bcrypt.Cost = n }},
+	} {
+		value := os.Getenv(param.env)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("password: invalid %v %q", param.env, value)
+		}
+		param.set(n)
+	}
+
+	if err := argon.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.validate(); err != nil {
+		return nil, err
+	}
+
+	switch algorithm := os.Getenv("password_hasher"); algorithm {
+	case "", "argon2id":
+		return NewManager(argon, bcrypt), nil
+	case "bcrypt":
+		return NewManager(bcrypt, argon), nil
+	default:
+		return nil, fmt.Errorf("password: unknown password_hasher %q", algorithm)
+	}
+}
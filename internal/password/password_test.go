@@ -0,0 +1,130 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+// cheap parameters, the tests are about the rehash decision not the cost
+var (
+	testArgon2id = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testBcrypt   = Bcrypt{Cost: 4}
+)
+
+func mustHash(t *testing.T, hasher Hasher, password string) string {
+	t.Helper()
+
+	encoded, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestManagerVerifyRehash(t *testing.T) {
+	manager := NewManager(testArgon2id, testBcrypt)
+
+	otherArgon2id := testArgon2id
+	otherArgon2id.Iterations = 2
+
+	for _, tc := range []struct {
+		name    string
+		encoded string
+		rehash  bool
+	}{
+		{"current", mustHash(t, testArgon2id, "secret"), false},
+		{"current algorithm, other parameters", mustHash(t, otherArgon2id, "secret"), true},
+		{"legacy algorithm", mustHash(t, testBcrypt, "secret"), true},
+		{"legacy algorithm, other cost", mustHash(t, Bcrypt{Cost: 5}, "secret"), true},
+	} {
+		ok, rehash, err := manager.Verify("secret", tc.encoded)
+		if err != nil || !ok {
+			t.Errorf("%v: verify is %v, %v", tc.name, ok, err)
+		}
+		if rehash != tc.rehash {
+			t.Errorf("%v: rehash is %v, want %v", tc.name, rehash, tc.rehash)
+		}
+
+		// the wrong password is never rehashed
+		ok, rehash, err = manager.Verify("wrong", tc.encoded)
+		if err != nil || ok || rehash {
+			t.Errorf("%v: wrong password verified %v, rehash %v, err %v", tc.name, ok, rehash, err)
+		}
+	}
+}
+
+func TestManagerVerifyUnknownFormat(t *testing.T) {
+	manager := NewManager(testArgon2id, testBcrypt)
+
+	for _, encoded := range []string{"", "plain", "$scrypt$ln=15$abc$def"} {
+		ok, rehash, err := manager.Verify("secret", encoded)
+		if !errors.Is(err, ErrUnknownFormat) || ok || rehash {
+			t.Errorf("%q: verify is %v, rehash %v, err %v", encoded, ok, rehash, err)
+		}
+	}
+}
+
+func TestDecodeArgon2idMalformed(t *testing.T) {
+	for _, encoded := range []string{
+		"$argon2id$",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5$extra",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$version$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64;t=1;p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=4,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$!!!",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		if _, _, _, err := decodeArgon2id(encoded); err == nil {
+			t.Errorf("%q: decoded", encoded)
+		}
+
+		// a broken hash is outdated, so it's never kept by a rehash
+		if !testArgon2id.Outdated(encoded) {
+			t.Errorf("%q: not outdated", encoded)
+		}
+	}
+}
+
+func TestDecodeArgon2idRoundTrip(t *testing.T) {
+	encoded := mustHash(t, testArgon2id, "secret")
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params.Memory != testArgon2id.Memory || params.Iterations != testArgon2id.Iterations ||
+		params.Parallelism != testArgon2id.Parallelism ||
+		uint32(len(salt)) != testArgon2id.SaltLength || uint32(len(key)) != testArgon2id.KeyLength {
+		t.Errorf("decoded %+v, salt %d, key %d bytes", params, len(salt), len(key))
+	}
+}
+
+// BenchmarkVerify compares the login cost of the former bcrypt cost 14
+// with the argon2id default
+func BenchmarkVerify(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		hasher Hasher
+	}{
+		{"bcrypt-14", DefaultBcrypt},
+		{"argon2id", DefaultArgon2id},
+	} {
+		encoded, err := bc.hasher.Hash("correct horse battery staple")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				if ok, err := bc.hasher.Verify("correct horse battery staple", encoded); !ok || err != nil {
+					b.Fatal(ok, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/storage"
+)
+
+const (
+	JOB_KIND_COURSE_UPLOAD = "course:process-upload"
+
+	COURSE_FILE_STAGING  = "staging"
+	COURSE_FILE_READY    = "ready"
+	COURSE_FILE_REJECTED = "rejected"
+
+	defaultMaxCourseFileBytes = 20 << 20
+)
+
+var allowedCourseMIME = []string{
+	"application/pdf",
+	"application/zip",
+	"image/png",
+	"image/jpeg",
+	"text/plain",
+}
+
+type CourseUploadPayload struct {
+	CourseID uuid.UUID `json:"course_id"`
+	FileID   string    `json:"file_id"`
+}
+
+func maxCourseFileBytes() int64 {
+	n, err := strconv.ParseInt(os.Getenv("course_max_upload_bytes"), 10, 64)
+	if err != nil || n <= 0 {
+		return defaultMaxCourseFileBytes
+	}
+	return n
+}
+
+// CourseStagingKey is where CreateCourse puts the upload before validation
+func CourseStagingKey(fileID string) string {
+	return "staging/" + fileID
+}
+
+// CourseFileKey is the permanent place of the validated course file
+func CourseFileKey(fileID string) string {
+	return "courses/" + fileID
+}
+
+// ProcessCourseUpload validates the staged course file (size & sniffed MIME),
+// then moves it from the staging key to the permanent course key
+func ProcessCourseUpload(s *server.Server) Handler {
+	return func(ctx context.Context, job database.Job) error {
+		var payload CourseUploadPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(err)
+		}
+
+		stagingKey := CourseStagingKey(payload.FileID)
+
+		rejectReason, err := validateCourseFile(ctx, s.Storage, stagingKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+				return Permanent(err)
+			}
+			return err
+		}
+
+		if rejectReason != "" {
+			log.Printf("COURSE FILE REJECTED: course=%v reason=%s\n", payload.CourseID, rejectReason)
+			if err := s.Storage.Delete(ctx, stagingKey); err != nil {
+				log.Println(err)
+			}
+
+			return s.Queries.UpdateCourseFileStatus(ctx, database.UpdateCourseFileStatusParams{
+				ID:         payload.CourseID,
+				FileStatus: COURSE_FILE_REJECTED,
+			})
+		}
+
+		if err := storage.Move(ctx, s.Storage, stagingKey, CourseFileKey(payload.FileID)); err != nil {
+			return err
+		}
+
+		return s.Queries.UpdateCourseFileStatus(ctx, database.UpdateCourseFileStatusParams{
+			ID:         payload.CourseID,
+			FileStatus: COURSE_FILE_READY,
+		})
+	}
+}
+
+// validateCourseFile returns a non-empty reason when the file must be rejected,
+// error only for the failure that is worth retrying
+func validateCourseFile(ctx context.Context, blob storage.Blob, key string) (string, error) {
+	info, err := blob.Stat(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size > maxCourseFileBytes() {
+		return fmt.Sprintf("file too large (%d bytes)", info.Size), nil
+	}
+
+	obj, _, err := blob.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(obj, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	mimeType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	if !slices.Contains(allowedCourseMIME, mimeType) {
+		return fmt.Sprintf("mime type %q not allowed", mimeType), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,193 @@
+// Package queue
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+)
+
+const (
+	JOB_STATUS_PENDING = "pending"
+	JOB_STATUS_RUNNING = "running"
+	JOB_STATUS_DONE    = "done"
+	JOB_STATUS_DEAD    = "dead"
+
+	defaultMaxAttempts = 5
+)
+
+var (
+	pollInterval   = 2 * time.Second
+	jobTimeout     = 5 * time.Minute
+	staleAfter     = 15 * time.Minute
+	backoffBase    = 30 * time.Second
+	backoffMaximum = time.Hour
+)
+
+// Handler does the work for one job kind. Returning an error schedules
+// a retry, unless the error is wrapped with Permanent
+type Handler func(ctx context.Context, job database.Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not worth retrying,
+// the job goes straight to the dead state
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type Queue struct {
+	Server   *server.Server
+	handlers map[string]Handler
+}
+
+func NewQueue(s *server.Server) *Queue {
+	return &Queue{
+		Server:   s,
+		handlers: make(map[string]Handler),
+	}
+}
+
+func (q *Queue) Register(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+// Enqueue takes the queries as argument, so the job can be written
+// inside the same transaction (utils.WithTX) as the data it points to
+func Enqueue(ctx context.Context, qtx *database.Queries, kind string, payload any) (database.Job, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return database.Job{}, err
+	}
+
+	return qtx.EnqueueJob(ctx, database.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     payloadBytes,
+		MaxAttempts: defaultMaxAttempts,
+	})
+}
+
+// Backoff grows exponentially from backoffBase, capped at backoffMaximum
+func Backoff(attempts int32) time.Duration {
+	d := time.Duration(float64(backoffBase) * math.Pow(2, float64(attempts-1)))
+	if d <= 0 || d > backoffMaximum {
+		return backoffMaximum
+	}
+	return d
+}
+
+// Start spawns the workers & the stale job reaper, every worker claims
+// a job with "FOR UPDATE SKIP LOCKED", so they never pick the same row
+func (q *Queue) Start(ctx context.Context, workers int) {
+	log.Printf("JOB QUEUE RUNNING: %d workers\n", workers)
+
+	for i := range workers {
+		go q.work(ctx, i)
+	}
+
+	go q.reapStaleJobs(ctx)
+}
+
+func (q *Queue) work(ctx context.Context, workerID int) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// drain the queue, only sleep when there is nothing to claim
+		for q.runNext(ctx, workerID) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) runNext(ctx context.Context, workerID int) bool {
+	query := q.Server.Queries
+
+	job, err := query.ClaimNextJob(ctx)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println(err)
+		}
+		return false
+	}
+
+	var jobErr error
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		jobErr = Permanent(fmt.Errorf("no handler registered for job kind %q", job.Kind))
+	} else {
+		jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+		jobErr = handler(jobCtx, job)
+		cancel()
+	}
+
+	if jobErr == nil {
+		if err := query.CompleteJob(ctx, job.ID); err != nil {
+			log.Println(err)
+		}
+		return true
+	}
+
+	var permanent *permanentError
+	if errors.As(jobErr, &permanent) || job.Attempts >= job.MaxAttempts {
+		log.Printf("JOB DEAD: worker=%d job=%v kind=%s err=%v\n", workerID, job.ID, job.Kind, jobErr)
+		if err := query.BuryJob(ctx, database.BuryJobParams{
+			ID:        job.ID,
+			LastError: jobErr.Error(),
+		}); err != nil {
+			log.Println(err)
+		}
+		return true
+	}
+
+	log.Printf("JOB RETRY: worker=%d job=%v kind=%s attempt=%d err=%v\n", workerID, job.ID, job.Kind, job.Attempts, jobErr)
+	if err := query.RetryJob(ctx, database.RetryJobParams{
+		ID:        job.ID,
+		RunAt:     time.Now().Add(Backoff(job.Attempts)),
+		LastError: jobErr.Error(),
+	}); err != nil {
+		log.Println(err)
+	}
+
+	return true
+}
+
+// reapStaleJobs puts back the jobs that stuck in "running",
+// mostly because the worker died in the middle of the job
+func (q *Queue) reapStaleJobs(ctx context.Context) {
+	ticker := time.NewTicker(staleAfter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Println("CLEANER CHECKPOINT: Stale Jobs")
+			if err := q.Server.Queries.RequeueStaleJobs(ctx, sql.NullTime{
+				Time:  time.Now().Add(-staleAfter),
+				Valid: true,
+			}); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+const (
+	apiKeyPrefix   = "rbk_"
+	apiTokenPrefix = "rbt_"
+	// the part of the key kept in clear, so the user can tell the keys apart
+	apiKeyShownLength = 8
+)
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid or revoked api key")
+	ErrInvalidAPIToken = errors.New("invalid or expired bearer token")
+	ErrInvalidScope    = errors.New("the scope is not part of the user's permissions")
+)
+
+// ScopesFor is every scope the roles may hand to an api key, the same
+// resource:action strings as the role permissions
+func (s *Server) ScopesFor(ctx context.Context, roles []string) ([]string, error) {
+	var scopes []string
+	for _, role := range roles {
+		permissions, err := s.Permissions.Of(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		for _, perm := range permissions {
+			if !slices.Contains(scopes, perm) {
+				scopes = append(scopes, perm)
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// IssueAPIKey returns the key in clear only this once, the table keeps the
+// sha256. The scopes can't go past the permissions of the user's roles,
+// qtx is the transaction of the caller
+func (s *Server) IssueAPIKey(ctx context.Context, qtx *database.Queries, userID uuid.UUID, name string, scopes []string, expireAt sql.NullTime) (string, database.ApiKey, error) {
+	roles, err := s.LoadUserRoles(ctx, userID)
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	allowed, err := s.ScopesFor(ctx, roles)
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	if len(scopes) == 0 {
+		return "", database.ApiKey{}, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return "", database.ApiKey{}, ErrInvalidScope
+		}
+	}
+
+	key, hash, err := tokens.GeneratePrefixed(apiKeyPrefix)
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	apiKey, err := qtx.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		UserID:   userID,
+		Name:     name,
+		Prefix:   key[:len(apiKeyPrefix)+apiKeyShownLength],
+		KeyHash:  hash,
+		Scopes:   scopes,
+		ExpireAt: expireAt,
+	})
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	return key, apiKey, nil
+}
+
+// ExchangeAPIKey trades the long lived key for the short lived bearer
+// token, the key itself never goes along with the api requests
+func (s *Server) ExchangeAPIKey(ctx context.Context, key string, ttl time.Duration) (string, database.ApiKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", database.ApiKey{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.Queries.GetActiveAPIKeyByHash(ctx, tokens.Hash(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", database.ApiKey{}, ErrInvalidAPIKey
+		}
+		return "", database.ApiKey{}, err
+	}
+
+	now := time.Now()
+	if apiKey.ExpireAt.Valid && now.After(apiKey.ExpireAt.Time) {
+		return "", database.ApiKey{}, ErrInvalidAPIKey
+	}
+
+	// the expired tokens are useless, cleaned up on the way
+	if err := s.Queries.DeleteExpiredAPITokens(ctx, now); err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	token, hash, err := tokens.GeneratePrefixed(apiTokenPrefix)
+	if err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	// the token never outlives its key
+	expireAt := now.Add(ttl)
+	if apiKey.ExpireAt.Valid && apiKey.ExpireAt.Time.Before(expireAt) {
+		expireAt = apiKey.ExpireAt.Time
+	}
+
+	if _, err := s.Queries.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ApiKeyID:  apiKey.ID,
+		TokenHash: hash,
+		ExpireAt:  expireAt,
+	}); err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	if err := s.Queries.UpdateAPIKeyLastUsed(ctx, apiKey.ID); err != nil {
+		return "", database.ApiKey{}, err
+	}
+
+	return token, apiKey, nil
+}
+
+// AuthenticateBearer builds the same Claims as the web session, with the
+// scopes of the key on top. The roles are loaded on every request, so
+// removing a role takes effect without revoking the keys
+func (s *Server) AuthenticateBearer(ctx context.Context, token string) (*Claims, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	row, err := s.Queries.GetAPITokenWithKey(ctx, tokens.Hash(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(row.ExpireAt) || (row.KeyExpireAt.Valid && now.After(row.KeyExpireAt.Time)) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	roles, err := s.LoadUserRoles(ctx, row.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		UserID: row.UserID,
+		Roles:  roles,
+		Scopes: row.Scopes,
+	}, nil
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"strings"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -13,58 +13,72 @@ import (
 type Claims struct {
 	UserID uuid.UUID
 	Roles  []string
+	// ActiveRole is the one role the web session acts as, picked among
+	// Roles. Empty on the api requests, the scopes narrow those down
+	ActiveRole string
+	// Scopes narrows the roles down for the api key requests,
+	// nil on the web session (the roles decide alone)
+	Scopes []string
+	// Impersonator is the admin viewing the app as UserID, uuid.Nil
+	// outside of the impersonation
+	Impersonator uuid.UUID
 }
 
-var Permissions = map[string][]string{
-	"admin": {
-		"adminPanelPages:view",
-		"users:*",
-		"teachers:*",
-		"students:*",
-		"studentCreatePage:view",
-	},
-	"teacher": {
-		"homePage:view",
-		"coursePage:view",
-		"teachers:view",
-		"courses:*",
-	},
-	"student": {
-		"homePage:view",
-		"students:view",
-	},
+// matchPermission is the unconditional match, the permissions with a
+// condition only count in Authorize, where the record is known
+func matchPermission(permission, resource, action string) bool {
+	r, a, condition, ok := splitPermission(permission)
+	return ok && condition == "" && matchResourceAction(r, a, resource, action)
 }
 
-func matchPermission(permission, resource, action string) bool {
-	if permission == "*" {
-		return true
+// Can reads the permissions of the roles from the PermissionStore, when
+// the store can't be read nothing is allowed
+func (s *Server) Can(claims *Claims, resource, action string) (bool, string) {
+	if claims.Scopes != nil && !PermissionsAllow(claims.Scopes, resource, action) {
+		return false, ""
 	}
 
-	parts := strings.SplitN(permission, ":", 2)
-	if len(parts) != 2 {
-		return false
+	roles := claims.Roles
+	if claims.ActiveRole != "" {
+		roles = []string{claims.ActiveRole}
 	}
 
-	r, a := parts[0], parts[1]
+	for _, role := range roles {
+		permissions, err := s.Permissions.Of(context.Background(), role)
+		if err != nil {
+			log.Println(err)
+			return false, ""
+		}
 
-	if r != resource {
-		return false
+		if PermissionsAllow(permissions, resource, action) {
+			return true, role
+		}
 	}
 
-	return a == action || a == "*"
+	return false, ""
 }
 
-func (s *Server) Can(claims *Claims, resource, action string) (bool, string) {
-	for _, role := range claims.Roles {
-		permissions := Permissions[role]
-		for _, perm := range permissions {
-			if matchPermission(perm, resource, action) {
-				return true, role
-			}
+// the fallback order of DefaultActiveRole, the least privileged first
+var activeRoleOrder = []string{"student", "teacher", "admin"}
+
+// DefaultActiveRole is preferred when the user holds it, otherwise the
+// least privileged role the user holds. Empty without any role
+func DefaultActiveRole(roles []string, preferred string) string {
+	if slices.Contains(roles, preferred) {
+		return preferred
+	}
+
+	for _, role := range activeRoleOrder {
+		if slices.Contains(roles, role) {
+			return role
 		}
 	}
 
-	return false, ""
+	if len(roles) > 0 {
+		return roles[0]
+	}
+
+	return ""
 }
 
 func (s *Server) LoadUserRoles(context context.Context, userID uuid.UUID) ([]string, error) {
@@ -98,9 +112,18 @@ func (s *Server) MiddlewareAuthZ(next echo.HandlerFunc) echo.HandlerFunc {
 			)
 		}
 
+		// the role picked in the session only counts while the user
+		// still holds it
+		activeRole, _ := c.Get("active_role").(string)
+
+		// set by the web session while an admin views the app as the user
+		impersonator, _ := c.Get("impersonator_id").(uuid.UUID)
+
 		c.Set("claims", &Claims{
-			UserID: userID,
-			Roles:  roles,
+			UserID:       userID,
+			Roles:        roles,
+			ActiveRole:   DefaultActiveRole(roles, activeRole),
+			Impersonator: impersonator,
 		})
 
 		return next(c)
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+const (
+	// failures older than the window don't count anymore
+	loginFailureWindow = 15 * time.Minute
+	loginMaxFailures   = 5
+
+	// the lockout doubles on every lockout of the account, until the
+	// account logs in successfully or the admin unlocks it
+	loginLockoutBase = 5 * time.Minute
+	loginLockoutMax  = 24 * time.Hour
+
+	// the answer of a failed login slows down with every failure
+	loginDelayStep = 500 * time.Millisecond
+	loginDelayMax  = 4 * time.Second
+)
+
+// LoginLockedUntil reports whether the account is locked out right now
+func (s *Server) LoginLockedUntil(ctx context.Context, userID uuid.UUID) (time.Time, bool, error) {
+	lockout, err := s.Queries.GetLoginLockout(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+
+	if !lockout.LockedUntil.Valid || time.Now().After(lockout.LockedUntil.Time) {
+		return time.Time{}, false, nil
+	}
+
+	return lockout.LockedUntil.Time, true, nil
+}
+
+// RecordLoginFailure counts the failed attempt against the account (not
+// the ip, so rotating the ip doesn't help), locks it out after
+// loginMaxFailures & returns how long the caller should hold the answer
+func (s *Server) RecordLoginFailure(ctx context.Context, userID uuid.UUID, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	lockout, err := s.Queries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		UserID:       userID,
+		LastFailedAt: now,
+		WindowStart:  now.Add(-loginFailureWindow),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if lockout.FailedCount >= loginMaxFailures {
+		lockedUntil := now.Add(lockoutDuration(lockout.LockoutCount))
+
+		if err := s.Queries.LockLoginAccount(ctx, database.LockLoginAccountParams{
+			UserID:      userID,
+			LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		}); err != nil {
+			return 0, err
+		}
+
+		log.Printf(
+			"LOGIN LOCKOUT: user %v locked until %v after %d failed attempts, the last from %s",
+			userID, lockedUntil.Format(time.DateTime), lockout.FailedCount, ip,
+		)
+	}
+
+	return min(time.Duration(lockout.FailedCount)*loginDelayStep, loginDelayMax), nil
+}
+
+// ClearLoginFailures forgets the failures after the successful login
+func (s *Server) ClearLoginFailures(ctx context.Context, userID uuid.UUID) error {
+	return s.Queries.DeleteLoginLockout(ctx, userID)
+}
+
+func lockoutDuration(lockoutCount int32) time.Duration {
+	d := loginLockoutBase
+	for range lockoutCount {
+		d *= 2
+		if d >= loginLockoutMax {
+			return loginLockoutMax
+		}
+	}
+	return d
+}
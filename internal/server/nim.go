@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+const (
+	defaultNimYearDigits   = 4
+	defaultNimSerialWidth  = 4
+	defaultNimReuseAfter   = 30 * 24 * time.Hour
+	maxNimLength           = 20
+	nimReuseDisabledMarker = "off"
+)
+
+var ErrNimExhausted = errors.New("cannot allocate the nim, the serial of the major is exhausted for this year")
+
+// NimFormat is the nim layout: year + major code + zero padded serial,
+// e.g. 2025TI0001 with the default 4 year digits & 4 serial width
+type NimFormat struct {
+	YearDigits  int
+	SerialWidth int
+}
+
+// NimFormatFromEnv reads nim_year_digits (2 or 4) & nim_serial_width,
+// falls back to the default on the invalid value
+func NimFormatFromEnv() NimFormat {
+	f := NimFormat{
+		YearDigits:  defaultNimYearDigits,
+		SerialWidth: defaultNimSerialWidth,
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("nim_year_digits")); err == nil && (n == 2 || n == 4) {
+		f.YearDigits = n
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("nim_serial_width")); err == nil && n > 0 && n <= 8 {
+		f.SerialWidth = n
+	}
+
+	return f
+}
+
+// Prefix is the cohort part of the nim (year + major code)
+func (f NimFormat) Prefix(year int, majorCode string) string {
+	yearStr := fmt.Sprintf("%04d", year)
+	return yearStr[len(yearStr)-f.YearDigits:] + strings.ToUpper(majorCode)
+}
+
+func (f NimFormat) Format(year int, majorCode string, serial int) (string, error) {
+	if serial <= 0 || len(strconv.Itoa(serial)) > f.SerialWidth {
+		return "", ErrNimExhausted
+	}
+
+	nim := fmt.Sprintf("%s%0*d", f.Prefix(year, majorCode), f.SerialWidth, serial)
+	if len(nim) > maxNimLength {
+		return "", fmt.Errorf("nim %q exceeds %d characters", nim, maxNimLength)
+	}
+
+	return nim, nil
+}
+
+// Owns reports whether the nim has the layout of the cohort, only those are
+// safe to hand out again (the legacy numeric nim never goes back to the pool)
+func (f NimFormat) Owns(nim string, year int, majorCode string) bool {
+	serial, ok := strings.CutPrefix(nim, f.Prefix(year, majorCode))
+	if !ok || len(serial) != f.SerialWidth {
+		return false
+	}
+
+	_, err := strconv.Atoi(serial)
+	return err == nil
+}
+
+// Pattern is Owns as a regexp, the released nim of another layout (the
+// format changed meanwhile) stays in the pool instead of being claimed
+func (f NimFormat) Pattern(year int, majorCode string) string {
+	return fmt.Sprintf("^%s[0-9]{%d}$", regexp.QuoteMeta(f.Prefix(year, majorCode)), f.SerialWidth)
+}
+
+// nimReuseAfter is how long a released nim rests before going to another
+// student, nim_reuse_after=off never reuses
+func nimReuseAfter() (time.Duration, bool) {
+	v := os.Getenv("nim_reuse_after")
+	if v == nimReuseDisabledMarker {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return defaultNimReuseAfter, true
+	}
+
+	return d, true
+}
+
+// AllocateNim hands out the nim for the new student of the major, it has to
+// be called inside the student creation tx. A released nim of the same
+// cohort is claimed first (skip locked, so the concurrent tx never claims
+// the same one), otherwise the serial of nim_sequences goes up, the upsert
+// keeps the sequence row locked until the tx ends
+func AllocateNim(ctx context.Context, qtx *database.Queries, major string, year int) (string, error) {
+	m, err := qtx.GetMajorByName(ctx, major)
+	if err != nil {
+		return "", err
+	}
+
+	format := NimFormatFromEnv()
+
+	if reuseAfter, ok := nimReuseAfter(); ok {
+		nim, err := qtx.ClaimReleasedNim(ctx, database.ClaimReleasedNimParams{
+			Year:       int32(year),
+			MajorCode:  m.Code,
+			ReleasedAt: time.Now().Add(-reuseAfter),
+			Pattern:    format.Pattern(year, m.Code),
+		})
+		if err == nil {
+			return nim, nil
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	serial, err := qtx.NextNimSerial(ctx, database.NextNimSerialParams{
+		Year:      int32(year),
+		MajorCode: m.Code,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return format.Format(year, m.Code, int(serial))
+}
+
+// ReleaseNim puts the nim of the deleted student back to the pool of its
+// cohort, the nim of another layout is simply dropped
+func ReleaseNim(ctx context.Context, qtx *database.Queries, nim, major string, year int) error {
+	m, err := qtx.GetMajorByName(ctx, major)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if !NimFormatFromEnv().Owns(nim, year, m.Code) {
+		return nil
+	}
+
+	return qtx.ReleaseNim(ctx, database.ReleaseNimParams{
+		Nim:       nim,
+		Year:      int32(year),
+		MajorCode: m.Code,
+	})
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+// testDB is the postgres of db_url, migrated up. Without db_url the test
+// is skipped
+func testDB(t *testing.T) (*sql.DB, *database.Queries) {
+	t.Helper()
+
+	dbURL := os.Getenv("db_url")
+	if dbURL == "" {
+		t.Skip("db_url is not set, skipping the postgres test")
+	}
+
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn, database.New(conn)
+}
+
+// testMajor is a major of the test alone, the sequences & the pool of the
+// real majors are left alone
+func testMajor(t *testing.T, conn *sql.DB, q *database.Queries) database.Major {
+	t.Helper()
+	ctx := context.Background()
+
+	code := "T" + strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:5])
+	major, err := q.CreateMajor(ctx, database.CreateMajorParams{
+		Name: "nim test " + code,
+		Code: code,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		for _, query := range []string{
+			"DELETE FROM nim_released WHERE major_code = $1",
+			"DELETE FROM nim_sequences WHERE major_code = $1",
+			"DELETE FROM majors WHERE code = $1",
+		} {
+			if _, err := conn.Exec(query, code); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	return major
+}
+
+// allocateConcurrently runs n student creation txs at once, each one holds
+// its nim a moment before the commit so the others run into its locks
+func allocateConcurrently(t *testing.T, conn *sql.DB, q *database.Queries, major string, year, n int) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	nims := make([]string, n)
+	errs := make(chan error, n)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			tx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+
+			nim, err := AllocateNim(ctx, q.WithTx(tx), major, year)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if err := tx.Commit(); err != nil {
+				errs <- err
+				return
+			}
+			nims[i] = nim
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	return nims
+}
+
+func assertUniqueNims(t *testing.T, nims []string) {
+	t.Helper()
+
+	seen := map[string]bool{}
+	for _, nim := range nims {
+		if seen[nim] {
+			t.Errorf("nim %v handed out twice", nim)
+		}
+		seen[nim] = true
+	}
+}
+
+func TestAllocateNimConcurrent(t *testing.T) {
+	conn, q := testDB(t)
+	major := testMajor(t, conn, q)
+	ctx := context.Background()
+	year := time.Now().Year()
+
+	t.Setenv("nim_year_digits", "4")
+	t.Setenv("nim_serial_width", "4")
+	t.Setenv("nim_reuse_after", "1h")
+
+	first := allocateConcurrently(t, conn, q, major.Name, year, 20)
+	assertUniqueNims(t, first)
+
+	// half of them back to the pool, old enough to be handed out again
+	for _, nim := range first[:10] {
+		if err := ReleaseNim(ctx, q, nim, major.Name, year); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := conn.Exec(
+		"UPDATE nim_released SET released_at = released_at - INTERVAL '2 days' WHERE major_code = $1",
+		major.Code,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// the released ten are claimed once each, the others take new serials
+	second := allocateConcurrently(t, conn, q, major.Name, year, 20)
+	assertUniqueNims(t, second)
+
+	for _, nim := range first[:10] {
+		found := false
+		for _, other := range second {
+			found = found || other == nim
+		}
+		if !found {
+			t.Errorf("released nim %v was not reused", nim)
+		}
+	}
+}
+
+func TestAllocateNimKeepsOtherFormat(t *testing.T) {
+	conn, q := testDB(t)
+	major := testMajor(t, conn, q)
+	ctx := context.Background()
+	year := time.Now().Year()
+
+	t.Setenv("nim_reuse_after", "1h")
+	t.Setenv("nim_serial_width", "4")
+
+	// released under the 2 digits year layout
+	t.Setenv("nim_year_digits", "2")
+	old := NimFormatFromEnv()
+	oldNim, err := old.Format(year, major.Code, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReleaseNim(ctx, q, oldNim, major.Name, year); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Exec(
+		"UPDATE nim_released SET released_at = released_at - INTERVAL '2 days' WHERE major_code = $1",
+		major.Code,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// the layout changed, the old nim can't be claimed but stays pooled
+	t.Setenv("nim_year_digits", "4")
+	nims := allocateConcurrently(t, conn, q, major.Name, year, 1)
+
+	if !NimFormatFromEnv().Owns(nims[0], year, major.Code) {
+		t.Errorf("nim %v is not of the current layout", nims[0])
+	}
+
+	var pooled int
+	if err := conn.QueryRow(
+		"SELECT COUNT(*) FROM nim_released WHERE nim = $1", oldNim,
+	).Scan(&pooled); err != nil {
+		t.Fatal(err)
+	}
+
+	if pooled != 1 {
+		t.Errorf("released nim %v of the old layout was lost", oldNim)
+	}
+}
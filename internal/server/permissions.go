@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+const permissionsTTL = time.Minute
+
+// "resource:action[:condition]", the action may be the "*" wildcard, or
+// the "*" alone
+var permissionPattern = regexp.MustCompile(`^(\*|[a-zA-Z][a-zA-Z0-9]*:(\*|[a-zA-Z][a-zA-Z0-9]*)(:(own|room|major|enrolled))?)$`)
+
+func ValidPermission(permission string) bool {
+	return permissionPattern.MatchString(permission)
+}
+
+// PermissionsAllow reports whether one of the unconditional permissions
+// covers the resource & action
+func PermissionsAllow(permissions []string, resource, action string) bool {
+	return slices.ContainsFunc(permissions, func(perm string) bool {
+		return matchPermission(perm, resource, action)
+	})
+}
+
+// CanGrant reports whether the caller's own permissions (of the active
+// role) cover the permission, the editor can't hand out more than its user
+// holds, the same cap the api key scopes have
+func (s *Server) CanGrant(ctx context.Context, claims *Claims, permission string) (bool, error) {
+	resource, action, _, ok := splitPermission(permission)
+	if !ok {
+		return false, nil
+	}
+
+	if claims.Scopes != nil && !PermissionsAllow(claims.Scopes, resource, action) {
+		return false, nil
+	}
+
+	roles := claims.Roles
+	if claims.ActiveRole != "" {
+		roles = []string{claims.ActiveRole}
+	}
+
+	for _, role := range roles {
+		permissions, err := s.Permissions.Of(ctx, role)
+		if err != nil {
+			return false, err
+		}
+
+		if PermissionsAllow(permissions, resource, action) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PermissionStore is the cached roles & permissions tables, Can runs on
+// every request so it doesn't go to the database each time. The permission
+// editor calls Invalidate after every change, the ttl covers the other
+// process (webserver & apiserver don't share the memory)
+type PermissionStore struct {
+	queries *database.Queries
+
+	mu          sync.RWMutex
+	roles       []database.Role
+	permissions map[string][]string
+	expireAt    time.Time
+	// generation is bumped by Invalidate, a load that started before it
+	// read the tables before the change and is thrown away
+	generation uint64
+}
+
+func NewPermissionStore(queries *database.Queries) *PermissionStore {
+	return &PermissionStore{queries: queries}
+}
+
+func (p *PermissionStore) Roles(ctx context.Context) ([]database.Role, error) {
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.roles, nil
+}
+
+func (p *PermissionStore) HasRole(ctx context.Context, role string) bool {
+	roles, err := p.Roles(ctx)
+	return err == nil && slices.ContainsFunc(roles, func(r database.Role) bool {
+		return r.Name == role
+	})
+}
+
+// Of is the permissions of the role, nil for the unknown role
+func (p *PermissionStore) Of(ctx context.Context, role string) ([]string, error) {
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.permissions[role], nil
+}
+
+func (p *PermissionStore) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expireAt = time.Time{}
+	p.generation++
+}
+
+func (p *PermissionStore) load(ctx context.Context) error {
+	for {
+		p.mu.RLock()
+		fresh := time.Now().Before(p.expireAt)
+		generation := p.generation
+		p.mu.RUnlock()
+
+		if fresh {
+			return nil
+		}
+
+		roles, err := p.queries.GetRolesAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		rows, err := p.queries.GetRolePermissionsAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		permissions := make(map[string][]string, len(roles))
+		for _, row := range rows {
+			permissions[row.Role] = append(permissions[row.Role], row.Permission)
+		}
+
+		p.mu.Lock()
+		stale := p.generation != generation
+		if !stale {
+			p.roles = roles
+			p.permissions = permissions
+			p.expireAt = time.Now().Add(permissionsTTL)
+		}
+		p.mu.Unlock()
+
+		// invalidated while loading, the change may be missing: load again
+		if !stale {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+// the conditions a permission may end with, "students:view:room" is the
+// students:view of the students in the user's own room
+const (
+	ConditionOwn      = "own"
+	ConditionRoom     = "room"
+	ConditionMajor    = "major"
+	ConditionEnrolled = "enrolled"
+)
+
+// Target is the record a policy is checked against, the zero fields are
+// the attributes the record doesn't have
+type Target struct {
+	OwnerID  uuid.UUID
+	RoomID   uuid.UUID
+	Major    string
+	CourseID uuid.UUID
+}
+
+// subject is the room & major of the requesting user, only loaded when a
+// room or major condition needs them
+type subject struct {
+	loaded bool
+	roomID uuid.UUID
+	major  string
+}
+
+func splitPermission(permission string) (resource, action, condition string, ok bool) {
+	if permission == "*" {
+		return "*", "*", "", true
+	}
+
+	parts := strings.Split(permission, ":")
+	switch len(parts) {
+	case 2:
+		return parts[0], parts[1], "", true
+	case 3:
+		return parts[0], parts[1], parts[2], true
+	}
+
+	return "", "", "", false
+}
+
+func matchResourceAction(r, a, resource, action string) bool {
+	if r == "*" && a == "*" {
+		return true
+	}
+
+	return r == resource && (a == action || a == "*")
+}
+
+// Authorize is Can for one record. The plain permission allows every
+// record, the conditional one only the records matching the user:
+// "own" the user's own, "room" & "major" the ones of the user's room
+// or major, "enrolled" the ones of the courses the user is enrolled to.
+// Handlers declare the policy with the record's attributes
+// instead of comparing the ids themselves
+func (s *Server) Authorize(ctx context.Context, claims *Claims, resource, action string, target Target) (bool, error) {
+	sub := &subject{}
+
+	if claims.Scopes != nil {
+		allowed, err := s.allows(ctx, claims, sub, claims.Scopes, resource, action, target)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+
+	roles := claims.Roles
+	if claims.ActiveRole != "" {
+		roles = []string{claims.ActiveRole}
+	}
+
+	for _, role := range roles {
+		permissions, err := s.Permissions.Of(ctx, role)
+		if err != nil {
+			return false, err
+		}
+
+		allowed, err := s.allows(ctx, claims, sub, permissions, resource, action, target)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	return false, nil
+}
+
+func (s *Server) allows(ctx context.Context, claims *Claims, sub *subject, permissions []string, resource, action string, target Target) (bool, error) {
+	for _, perm := range permissions {
+		r, a, condition, ok := splitPermission(perm)
+		if !ok || !matchResourceAction(r, a, resource, action) {
+			continue
+		}
+
+		switch condition {
+		case "":
+			return true, nil
+
+		case ConditionOwn:
+			if target.OwnerID != uuid.Nil && target.OwnerID == claims.UserID {
+				return true, nil
+			}
+
+		case ConditionRoom, ConditionMajor:
+			if err := s.loadSubject(ctx, claims.UserID, sub); err != nil {
+				return false, err
+			}
+
+			if condition == ConditionRoom && sub.roomID != uuid.Nil && sub.roomID == target.RoomID {
+				return true, nil
+			}
+
+			if condition == ConditionMajor && sub.major != "" && sub.major == target.Major {
+				return true, nil
+			}
+
+		case ConditionEnrolled:
+			if target.CourseID == uuid.Nil {
+				continue
+			}
+
+			n, err := s.Queries.CountActiveEnrollmentByUserID(ctx, database.CountActiveEnrollmentByUserIDParams{
+				UserID:   claims.UserID,
+				CourseID: target.CourseID,
+			})
+			if err != nil {
+				return false, err
+			}
+
+			if n > 0 {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// loadSubject reads the room & major of the student behind the user, the
+// users without a student record have neither
+func (s *Server) loadSubject(ctx context.Context, userID uuid.UUID, sub *subject) error {
+	if sub.loaded {
+		return nil
+	}
+	sub.loaded = true
+
+	student, err := s.Queries.GetStudentByUserId(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	room, err := s.Queries.GetStudentRoomById(ctx, student.RoomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	sub.roomID = room.ID
+	sub.major = room.Major
+	return nil
+}
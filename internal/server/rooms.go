@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+var ErrRoomsFull = errors.New("cannot assign to the major, due to the full student")
+
+// AssignRoom picks the least full room of the major, it has to be called
+// inside the student creation tx. The rooms of the major stay locked
+// until the tx ends, so the concurrent signups line up instead of
+// filling the same seat twice
+func AssignRoom(ctx context.Context, qtx *database.Queries, major string) (database.Room, error) {
+	rooms, err := qtx.LockRoomsByMajor(ctx, major)
+	if err != nil {
+		return database.Room{}, err
+	}
+
+	if len(rooms) == 0 {
+		return database.Room{}, ErrRoomsFull
+	}
+
+	room, err := qtx.GetLeastFullRoomByMajor(ctx, major)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Room{}, ErrRoomsFull
+		}
+		return database.Room{}, err
+	}
+
+	return room, nil
+}
@@ -10,11 +10,23 @@ import (
 	"time"
 
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/mailer"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/oidc"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/password"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/sessionstore"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/storage"
 )
 
 type Server struct {
-	Queries *database.Queries
-	DB      *sql.DB
+	Queries  *database.Queries
+	DB       *sql.DB
+	Storage  storage.Blob
+	Mailer   mailer.Mailer
+	Sessions sessionstore.SessionStore
+	// OIDC is nil when the single sign-on isn't configured
+	OIDC        *oidc.Provider
+	Permissions *PermissionStore
+	Passwords   *password.Manager
 }
 
 func GetServerConfig() (*Server, error) {
@@ -28,34 +40,48 @@ func GetServerConfig() (*Server, error) {
 		return nil, err
 	}
 
+	queries := database.New(conn)
+
+	passwords, err := password.NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// both the web & the api server send the account emails
+	mail, err := mailer.NewFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// the web & the api server look up the same sessions
+	sessions, err := sessionstore.NewFromEnv(queries)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Server{
-		Queries: database.New(conn),
-		DB:      conn,
+		Queries:     queries,
+		DB:          conn,
+		Mailer:      mail,
+		Sessions:    sessions,
+		Permissions: NewPermissionStore(queries),
+		Passwords:   passwords,
 	}, nil
 }
 
+// CleanStaleUserSessions sweeps the session store every 11 minutes, the
+// sessions idle for 10 minutes are logged out
 func (server *Server) CleanStaleUserSessions() {
 	log.Println("CLEANER RUNNNIG: Stale User Sessions")
 	ticker := time.NewTicker(11 * time.Minute)
 	ctx := context.Background()
-	query := server.Queries
 
 	go func() {
 		for range ticker.C {
 			log.Println("CLEANER CHECKPOINT: Stale User Sessions")
-			expireTime := time.Now().Local().Add(-10 * time.Minute)
-			sessions, err := query.GetSessionIDAll(ctx)
-			if err != nil {
+			if err := server.Sessions.Sweep(ctx, 10*time.Minute); err != nil {
 				log.Println(err)
 			}
-
-			for _, s := range sessions {
-				if !s.LastActivity.Local().Add(-7 * time.Hour).After(expireTime) {
-					if err := query.DeleteSessionByID(ctx, s.ID); err != nil {
-						log.Println(err)
-					}
-				}
-			}
 		}
 	}()
 }
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/oidc"
+)
+
+var (
+	ErrSSOUnverifiedEmail = errors.New("the identity provider hasn't verified the email")
+	ErrSSONoAccount       = errors.New("no account uses the email of the identity provider")
+	ErrSSONoRole          = errors.New("the groups of the identity provider leave the account without a role")
+)
+
+// NewOIDCFromEnv is nil when the single sign-on isn't configured
+func NewOIDCFromEnv() (*oidc.Provider, error) {
+	cfg, err := oidc.ConfigFromEnv()
+	if err != nil {
+		if errors.Is(err, oidc.ErrDisabled) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return oidc.NewProvider(cfg), nil
+}
+
+// LinkSSOUser finds the account of the identity. The first login links it
+// by the verified email, the later ones go by the issuer & subject, so a
+// changed email at the provider doesn't lose the account. No account is
+// created here, the accounts come with their student or teacher records
+func (s *Server) LinkSSOUser(ctx context.Context, claims *oidc.Claims) (uuid.UUID, error) {
+	issuer := s.OIDC.Config().Issuer
+
+	identity, err := s.Queries.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Issuer:  issuer,
+		Subject: claims.Subject,
+	})
+	if err == nil {
+		if err := s.Queries.UpdateUserIdentityLastLogin(ctx, identity.ID); err != nil {
+			return uuid.Nil, err
+		}
+		return identity.UserID, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, err
+	}
+
+	// an unverified email is whatever the user typed at the provider
+	if !claims.EmailVerified || claims.Email == "" {
+		return uuid.Nil, ErrSSOUnverifiedEmail
+	}
+
+	user, err := s.Queries.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrSSONoAccount
+		}
+		return uuid.Nil, err
+	}
+
+	// the link & the verified email go together, the same tx as
+	// SyncSSORoles (utils.WithTX can't be imported from here)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+	qtx := s.Queries.WithTx(tx)
+
+	if _, err := qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: claims.Subject,
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	// the provider verified the address, no need for the mailed link
+	if err := qtx.MarkUserEmailVerified(ctx, user.ID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+
+	log.Printf("SSO LINK: user %v linked to %v subject %v", user.ID, issuer, claims.Subject)
+
+	return user.ID, nil
+}
+
+// SyncSSORoles makes the roles of the role map follow the groups of the
+// provider. The roles outside the map are the app's own and left alone,
+// and without the groups claim in the token nothing changes
+func (s *Server) SyncSSORoles(ctx context.Context, userID uuid.UUID, groups []string) ([]string, error) {
+	current, err := s.LoadUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if groups == nil {
+		return current, nil
+	}
+
+	mapped := s.OIDC.MappedRoles()
+
+	// the roles live in the roles table, a role map onto a role nobody
+	// created yet maps onto nothing
+	var wanted []string
+	for _, role := range s.OIDC.Roles(groups) {
+		if !s.Permissions.HasRole(ctx, role) {
+			log.Printf("SSO ROLE: oidc_role_map names the unknown role %v", role)
+			continue
+		}
+		wanted = append(wanted, role)
+	}
+
+	var roles []string
+	for _, role := range current {
+		if !slices.Contains(mapped, role) || slices.Contains(wanted, role) {
+			roles = append(roles, role)
+		}
+	}
+	for _, role := range wanted {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	// the account can't log in without any role, it stays as it was
+	if len(roles) == 0 {
+		return nil, ErrSSONoRole
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	qtx := s.Queries.WithTx(tx)
+
+	for _, role := range current {
+		if slices.Contains(roles, role) {
+			continue
+		}
+		if err := qtx.DeleteUserRole(ctx, database.DeleteUserRoleParams{UserID: userID, Role: role}); err != nil {
+			return nil, err
+		}
+		log.Printf("SSO ROLE: %v removed from user %v", role, userID)
+	}
+
+	for _, role := range roles {
+		if slices.Contains(current, role) {
+			continue
+		}
+		if _, err := qtx.CreateUserRoles(ctx, database.CreateUserRolesParams{UserID: userID, Role: role}); err != nil {
+			return nil, err
+		}
+		log.Printf("SSO ROLE: %v added to user %v", role, userID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(roles)
+	return roles, nil
+}
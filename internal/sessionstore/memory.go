@@ -0,0 +1,170 @@
+package sessionstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+// Memory keeps the sessions in the process, keyed by the token hash
+// like the postgres store
+type Memory struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewMemory() *Memory {
+	return &Memory{sessions: map[string]*Session{}}
+}
+
+func (m *Memory) Create(ctx context.Context, params CreateParams) (string, Session, error) {
+	token, hash, err := tokens.Generate()
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	now := time.Now()
+	s := &Session{
+		ID:           uuid.New(),
+		UserID:       params.UserID,
+		CreatedAt:    now,
+		LastActivity: now,
+		ExpireAt:     now.Add(params.TTL),
+		UserAgent:    params.UserAgent,
+		IPAddress:    params.IPAddress,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[hash] = s
+	return token, *s, nil
+}
+
+func (m *Memory) Get(ctx context.Context, token string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[tokens.Hash(token)]
+	if !ok || token == "" {
+		return Session{}, ErrNotFound
+	}
+
+	return *s, nil
+}
+
+func (m *Memory) Touch(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.sessions[tokens.Hash(token)]; ok {
+		s.LastActivity = time.Now()
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, tokens.Hash(token))
+	return nil
+}
+
+func (m *Memory) ListActive(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var sessions []Session
+	for hash, s := range m.sessions {
+		// the expired are dropped here too, between the sweeps
+		if now.After(s.ExpireAt) {
+			delete(m.sessions, hash)
+			continue
+		}
+
+		if s.UserID == userID && s.Active(now) {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Compare(b.LastActivity.UnixNano(), a.LastActivity.UnixNano())
+	})
+
+	return sessions, nil
+}
+
+func (m *Memory) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.ID == id && s.UserID == userID {
+			s.IsRevoked = true
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *Memory) RevokeOthers(ctx context.Context, userID uuid.UUID, keepToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := tokens.Hash(keepToken)
+	for hash, s := range m.sessions {
+		if s.UserID == userID && hash != keep {
+			s.IsRevoked = true
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			s.IsRevoked = true
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) RevokeUsers(ctx context.Context, userIDs []uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if slices.Contains(userIDs, s.UserID) {
+			s.IsRevoked = true
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Sweep(ctx context.Context, idle time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for hash, s := range m.sessions {
+		if !s.Active(now) || now.Sub(s.LastActivity) > idle {
+			delete(m.sessions, hash)
+		}
+	}
+
+	return nil
+}
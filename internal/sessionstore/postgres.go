@@ -0,0 +1,121 @@
+package sessionstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+type Postgres struct {
+	q *database.Queries
+}
+
+func NewPostgres(q *database.Queries) *Postgres {
+	return &Postgres{q: q}
+}
+
+func fromRow(s database.Session) Session {
+	return Session{
+		ID:           s.ID,
+		UserID:       s.UserID,
+		CreatedAt:    s.CreatedAt,
+		LastActivity: s.LastActivity,
+		ExpireAt:     s.ExpireAt,
+		IsRevoked:    s.IsRevoked,
+		UserAgent:    s.UserAgent,
+		IPAddress:    s.IpAddress,
+	}
+}
+
+func (p *Postgres) Create(ctx context.Context, params CreateParams) (string, Session, error) {
+	token, hash, err := tokens.Generate()
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	row, err := p.q.CreateUserSession(ctx, database.CreateUserSessionParams{
+		TokenHash: hash,
+		UserID:    params.UserID,
+		ExpireAt:  time.Now().Add(params.TTL),
+		UserAgent: params.UserAgent,
+		IpAddress: params.IPAddress,
+	})
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	return token, fromRow(row), nil
+}
+
+func (p *Postgres) Get(ctx context.Context, token string) (Session, error) {
+	if token == "" {
+		return Session{}, ErrNotFound
+	}
+
+	row, err := p.q.GetUserSession(ctx, tokens.Hash(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Session{}, ErrNotFound
+		}
+		return Session{}, err
+	}
+
+	return fromRow(row), nil
+}
+
+func (p *Postgres) Touch(ctx context.Context, token string) error {
+	return p.q.UpdateLastActivityUserSession(ctx, tokens.Hash(token))
+}
+
+func (p *Postgres) Delete(ctx context.Context, token string) error {
+	return p.q.DeleteUserSession(ctx, tokens.Hash(token))
+}
+
+func (p *Postgres) ListActive(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := p.q.GetActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, fromRow(row))
+	}
+
+	return sessions, nil
+}
+
+func (p *Postgres) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	n, err := p.q.RevokeUserSessionByID(ctx, database.RevokeUserSessionByIDParams{
+		ID:     id,
+		UserID: userID,
+	})
+	return n > 0, err
+}
+
+func (p *Postgres) RevokeOthers(ctx context.Context, userID uuid.UUID, keepToken string) error {
+	return p.q.RevokeOtherUserSessions(ctx, database.RevokeOtherUserSessionsParams{
+		UserID:    userID,
+		TokenHash: tokens.Hash(keepToken),
+	})
+}
+
+func (p *Postgres) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return p.q.RevokeUserSessionsByUserID(ctx, userID)
+}
+
+func (p *Postgres) RevokeUsers(ctx context.Context, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return p.q.RevokeUserSessionsByUserIDs(ctx, userIDs)
+}
+
+func (p *Postgres) Sweep(ctx context.Context, idle time.Duration) error {
+	return p.q.SweepUserSessions(ctx, int32(idle.Seconds()))
+}
@@ -0,0 +1,76 @@
+// Package sessionstore keeps the login sessions server side. The client only
+// holds a random token, the store keeps its sha256, so a leaked table (or
+// a guess from the user id & login time) doesn't give a usable session.
+// The backend (postgres or memory) is picked from the environment
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+var ErrNotFound = errors.New("sessionstore: session not found")
+
+type Session struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	CreatedAt    time.Time
+	LastActivity time.Time
+	ExpireAt     time.Time
+	IsRevoked    bool
+	UserAgent    string
+	IPAddress    string
+}
+
+// Active reports whether the session can still authenticate the request
+func (s Session) Active(now time.Time) bool {
+	return !s.IsRevoked && now.Before(s.ExpireAt)
+}
+
+type CreateParams struct {
+	UserID    uuid.UUID
+	TTL       time.Duration
+	UserAgent string
+	IPAddress string
+}
+
+// SessionStore is looked up by the token from the cookie (or header),
+// the user level operations take the session id shown on the sessions page
+type SessionStore interface {
+	// Create returns the token for the client, it's not kept anywhere
+	Create(ctx context.Context, params CreateParams) (string, Session, error)
+	Get(ctx context.Context, token string) (Session, error)
+	Touch(ctx context.Context, token string) error
+	Delete(ctx context.Context, token string) error
+
+	ListActive(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RevokeOthers(ctx context.Context, userID uuid.UUID, keepToken string) error
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+	// RevokeUsers is RevokeAll of many users at once (a role...)
+	RevokeUsers(ctx context.Context, userIDs []uuid.UUID) error
+
+	// Sweep drops the revoked & expired sessions, and the ones without a
+	// request for longer than idle
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+// NewFromEnv reads session_store: postgres (default) or memory. The memory
+// store doesn't survive the restart & isn't shared between the servers,
+// it's for the tests and the local run
+func NewFromEnv(q *database.Queries) (SessionStore, error) {
+	switch driver := os.Getenv("session_store"); driver {
+	case "", "postgres":
+		return NewPostgres(q), nil
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("sessionstore: unknown session_store %q", driver)
+	}
+}
@@ -0,0 +1,318 @@
+package sessionstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+// the ttls are days apart from now, so the expiry holds whatever the
+// time zone of the database session is
+const (
+	liveTTL    = 48 * time.Hour
+	expiredTTL = -48 * time.Hour
+)
+
+// backend is one SessionStore under test with the helpers that reach
+// behind the interface
+type backend struct {
+	name  string
+	store SessionStore
+	// newUser returns a user the sessions can belong to
+	newUser func(t *testing.T) uuid.UUID
+	// stores reports whether the value is kept as the key of a session
+	stores func(t *testing.T, value string) bool
+}
+
+func memoryBackend() backend {
+	m := NewMemory()
+
+	return backend{
+		name:    "memory",
+		store:   m,
+		newUser: func(t *testing.T) uuid.UUID { return uuid.New() },
+		stores: func(t *testing.T, value string) bool {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			_, ok := m.sessions[value]
+			return ok
+		},
+	}
+}
+
+// postgresBackend is the postgres of db_url, migrated up. Without db_url
+// it's left out
+func postgresBackend(t *testing.T) (backend, bool) {
+	dbURL := os.Getenv("db_url")
+	if dbURL == "" {
+		return backend{}, false
+	}
+
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	q := database.New(conn)
+
+	return backend{
+		name:  "postgres",
+		store: NewPostgres(q),
+		newUser: func(t *testing.T) uuid.UUID {
+			user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+				Email:        "sessionstore-" + uuid.NewString() + "@test.local",
+				PasswordHash: "-",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the sessions go along with the user
+			t.Cleanup(func() {
+				if err := q.DeleteUserByID(context.Background(), user.ID); err != nil {
+					t.Error(err)
+				}
+			})
+			return user.ID
+		},
+		stores: func(t *testing.T, value string) bool {
+			var n int
+			if err := conn.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token_hash = $1`, value).Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			return n > 0
+		},
+	}, true
+}
+
+func backends(t *testing.T) []backend {
+	list := []backend{memoryBackend()}
+	if pg, ok := postgresBackend(t); ok {
+		list = append(list, pg)
+	} else {
+		t.Log("db_url is not set, only the memory store is tested")
+	}
+	return list
+}
+
+func create(t *testing.T, b backend, userID uuid.UUID, ttl time.Duration) (string, Session) {
+	t.Helper()
+
+	token, s, err := b.store.Create(context.Background(), CreateParams{
+		UserID:    userID,
+		TTL:       ttl,
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0",
+		IPAddress: "127.0.0.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, s
+}
+
+func activeIDs(t *testing.T, b backend, userID uuid.UUID) []uuid.UUID {
+	t.Helper()
+
+	sessions, err := b.store.ListActive(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func sameIDs(got []uuid.UUID, want ...uuid.UUID) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+
+	for _, b := range backends(t) {
+		t.Run(b.name, func(t *testing.T) {
+			t.Run("create & get", func(t *testing.T) {
+				user := b.newUser(t)
+				token, created := create(t, b, user, liveTTL)
+
+				got, err := b.store.Get(ctx, token)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.ID != created.ID || got.UserID != user || !got.Active(time.Now()) {
+					t.Errorf("get is %+v, want the active session %v of %v", got, created.ID, user)
+				}
+
+				for _, other := range []string{"", "not-a-token", tokens.Hash(token)} {
+					if _, err := b.store.Get(ctx, other); !errors.Is(err, ErrNotFound) {
+						t.Errorf("get %q: %v, want ErrNotFound", other, err)
+					}
+				}
+			})
+
+			t.Run("only the hash is kept", func(t *testing.T) {
+				token, _ := create(t, b, b.newUser(t), liveTTL)
+
+				if b.stores(t, token) {
+					t.Error("the raw token is kept")
+				}
+				if !b.stores(t, tokens.Hash(token)) {
+					t.Error("the token hash is not kept")
+				}
+			})
+
+			t.Run("touch", func(t *testing.T) {
+				token, created := create(t, b, b.newUser(t), liveTTL)
+				time.Sleep(20 * time.Millisecond)
+
+				if err := b.store.Touch(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := b.store.Get(ctx, token)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.LastActivity.After(created.LastActivity) {
+					t.Errorf("last activity is %v, created %v", got.LastActivity, created.LastActivity)
+				}
+			})
+
+			t.Run("revoke", func(t *testing.T) {
+				user, other := b.newUser(t), b.newUser(t)
+				token, s := create(t, b, user, liveTTL)
+
+				// another user can't revoke it by the id
+				if revoked, err := b.store.Revoke(ctx, other, s.ID); err != nil || revoked {
+					t.Fatalf("revoke by another user: %v, %v", revoked, err)
+				}
+
+				if revoked, err := b.store.Revoke(ctx, user, s.ID); err != nil || !revoked {
+					t.Fatalf("revoke: %v, %v", revoked, err)
+				}
+
+				got, err := b.store.Get(ctx, token)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Active(time.Now()) {
+					t.Error("the revoked session is still active")
+				}
+				if ids := activeIDs(t, b, user); len(ids) != 0 {
+					t.Errorf("the revoked session is listed: %v", ids)
+				}
+			})
+
+			t.Run("revoke others", func(t *testing.T) {
+				user := b.newUser(t)
+				keep, kept := create(t, b, user, liveTTL)
+				create(t, b, user, liveTTL)
+				create(t, b, user, liveTTL)
+
+				if err := b.store.RevokeOthers(ctx, user, keep); err != nil {
+					t.Fatal(err)
+				}
+				if ids := activeIDs(t, b, user); !sameIDs(ids, kept.ID) {
+					t.Errorf("active after revoke others: %v, want only %v", ids, kept.ID)
+				}
+			})
+
+			t.Run("revoke all & users", func(t *testing.T) {
+				first, second, bystander := b.newUser(t), b.newUser(t), b.newUser(t)
+				create(t, b, first, liveTTL)
+				create(t, b, first, liveTTL)
+				create(t, b, second, liveTTL)
+				_, kept := create(t, b, bystander, liveTTL)
+
+				if err := b.store.RevokeAll(ctx, first); err != nil {
+					t.Fatal(err)
+				}
+				if ids := activeIDs(t, b, first); len(ids) != 0 {
+					t.Errorf("active after revoke all: %v", ids)
+				}
+				if ids := activeIDs(t, b, second); len(ids) != 1 {
+					t.Errorf("revoke all reached another user: %v", ids)
+				}
+
+				if err := b.store.RevokeUsers(ctx, []uuid.UUID{second}); err != nil {
+					t.Fatal(err)
+				}
+				if ids := activeIDs(t, b, second); len(ids) != 0 {
+					t.Errorf("active after revoke users: %v", ids)
+				}
+				if ids := activeIDs(t, b, bystander); !sameIDs(ids, kept.ID) {
+					t.Errorf("revoke users reached another user: %v", ids)
+				}
+			})
+
+			t.Run("expiry & sweep", func(t *testing.T) {
+				user := b.newUser(t)
+				expiredToken, _ := create(t, b, user, expiredTTL)
+				revokedToken, revoked := create(t, b, user, liveTTL)
+				liveToken, live := create(t, b, user, liveTTL)
+
+				expired, err := b.store.Get(ctx, expiredToken)
+				if err == nil && expired.Active(time.Now()) {
+					t.Error("the expired session is active")
+				}
+
+				if _, err := b.store.Revoke(ctx, user, revoked.ID); err != nil {
+					t.Fatal(err)
+				}
+				if ids := activeIDs(t, b, user); !sameIDs(ids, live.ID) {
+					t.Errorf("active is %v, want only %v", ids, live.ID)
+				}
+
+				if err := b.store.Sweep(ctx, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, token := range []string{expiredToken, revokedToken} {
+					if _, err := b.store.Get(ctx, token); !errors.Is(err, ErrNotFound) {
+						t.Errorf("swept session: %v, want ErrNotFound", err)
+					}
+				}
+				if _, err := b.store.Get(ctx, liveToken); err != nil {
+					t.Errorf("the live session was swept: %v", err)
+				}
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				token, _ := create(t, b, b.newUser(t), liveTTL)
+
+				if err := b.store.Delete(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := b.store.Get(ctx, token); !errors.Is(err, ErrNotFound) {
+					t.Errorf("get after delete: %v, want ErrNotFound", err)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// testS3 is the MinIO of s3_test_endpoint, e.g. http://localhost:9000,
+// with the bucket created when it's missing. Without s3_test_endpoint
+// the test is skipped
+func testS3(t *testing.T) *S3 {
+	t.Helper()
+
+	endpoint := os.Getenv("s3_test_endpoint")
+	if endpoint == "" {
+		t.Skip("s3_test_endpoint is not set, skipping the s3 test")
+	}
+
+	s, err := NewS3(S3Config{
+		Endpoint:  endpoint,
+		Region:    os.Getenv("s3_test_region"),
+		Bucket:    envOr("s3_test_bucket", "rambanbelajar-test"),
+		AccessKey: envOr("s3_test_access_key", "minioadmin"),
+		SecretKey: envOr("s3_test_secret_key", "minioadmin"),
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucketURL := *s.endpoint
+	bucketURL.Path = "/" + s.cfg.Bucket
+
+	req, err := http.NewRequest(http.MethodHead, bucketURL.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		req, _ = http.NewRequest(http.MethodPut, bucketURL.String(), nil)
+		resp, err = s.do(req)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	return s
+}
+
+func TestS3PutGetStatDelete(t *testing.T) {
+	s := testS3(t)
+	ctx := context.Background()
+
+	key := "test/" + uuid.NewString() + "/course notes.pdf"
+	content := []byte(strings.Repeat("rambanbelajar ", 1000))
+	t.Cleanup(func() { s.Delete(ctx, key) })
+
+	if err := s.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(content)) || info.ContentType != "application/pdf" {
+		t.Errorf("stat is %+v", info)
+	}
+
+	obj, _, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+
+	got, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("get read %d bytes, want the %d put", len(got), len(content))
+	}
+
+	// the Range GET after a seek, what http.ServeContent does
+	if _, err := obj.Seek(-5, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tail, content[len(content)-5:]) {
+		t.Errorf("read after seek is %q", tail)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stat after delete: %v, want ErrNotFound", err)
+	}
+
+	// deleting the missing object is fine
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("second delete: %v", err)
+	}
+	if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get after delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	s := testS3(t)
+	ctx := context.Background()
+
+	key := "test/" + uuid.NewString()
+	content := []byte("signed content")
+	t.Cleanup(func() { s.Delete(ctx, key) })
+
+	if err := s.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := s.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no credentials but the url
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, content) {
+		t.Fatalf("signed url answered %v %q", resp.Status, got)
+	}
+
+	// the url is bound to the key
+	other := strings.Replace(signed, key, key+"x", 1)
+	resp, err = http.Get(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("signed url of another key answered %v", resp.Status)
+	}
+}
@@ -0,0 +1,111 @@
+// Package storage keeps the uploaded files behind the Blob interface,
+// the backend (local disk or S3 compatible) is picked from the environment
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("storage: object not found")
+	ErrInvalidKey  = errors.New("storage: invalid object key")
+	ErrNoSignedURL = errors.New("storage: the backend has no signed url")
+)
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Object is seekable, so it can be handed to http.ServeContent
+// and served with Range requests regardless of the backend
+type Object interface {
+	io.ReadSeekCloser
+}
+
+// Blob is the file storage used by the course uploads (and any future uploads),
+// keys are slash separated relative paths, e.g. "courses/<uuid>"
+type Blob interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (Object, ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// SignedURL is offered by the backend the client can reach by itself,
+	// the others return ErrNoSignedURL
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// ValidateKey rejects the key that may escape the storage root,
+// it must be a clean relative path without "..", "\" and empty segments
+func ValidateKey(key string) error {
+	if key == "" || len(key) > 512 {
+		return ErrInvalidKey
+	}
+
+	if strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return ErrInvalidKey
+	}
+
+	if path.Clean(key) != key {
+		return ErrInvalidKey
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+
+	return nil
+}
+
+// Move copies the object to the new key, then deletes the old one.
+// Backends don't share a rename, so this works for all of them
+func Move(ctx context.Context, blob Blob, srcKey, dstKey string) error {
+	obj, info, err := blob.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	if err := blob.Put(ctx, dstKey, obj, info.Size, info.ContentType); err != nil {
+		return err
+	}
+
+	return blob.Delete(ctx, srcKey)
+}
+
+// NewFromEnv picks the backend from "storage_driver" (local|s3)
+func NewFromEnv() (Blob, error) {
+	switch driver := os.Getenv("storage_driver"); driver {
+	case "", "local":
+		dir := os.Getenv("storage_local_path")
+		if dir == "" {
+			dir = os.Getenv("course_storage_path")
+		}
+		if dir == "" {
+			return nil, errors.New("error: cannot find storage_local_path in the environment")
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("s3_endpoint"),
+			Region:    os.Getenv("s3_region"),
+			Bucket:    os.Getenv("s3_bucket"),
+			AccessKey: os.Getenv("s3_access_key"),
+			SecretKey: os.Getenv("s3_secret_key"),
+			PathStyle: os.Getenv("s3_path_style") != "false",
+		})
+	default:
+		return nil, fmt.Errorf("error: unknown storage_driver %q", driver)
+	}
+}
@@ -0,0 +1,38 @@
+// Package tokens makes the random tokens handed to the client (session,
+// reset & verification links, api keys & bearer tokens). The client keeps
+// the token, the database only its hash
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const tokenBytes = 32
+
+// Generate returns 32 bytes from crypto/rand, base64url encoded, and its
+// hash
+func Generate() (token, hash string, err error) {
+	return GeneratePrefixed("")
+}
+
+// GeneratePrefixed is Generate with the prefix in front ("rbk_..."), the
+// hash covers the prefix too
+func GeneratePrefixed(prefix string) (token, hash string, err error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = prefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash is sha256 hex (64 chars), the token has enough entropy so it
+// doesn't need the slow password hash
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,253 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+// the ascii "12345678901234567890" of RFC 6238 Appendix B, base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// the SHA-1 column of Appendix B, the 8 digits codes cut to the last 6
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		want := tc.code[len(tc.code)-DIGITS:]
+
+		got, err := Code(rfcSecret, Step(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("code at %d is %v, want %v", tc.unix, got, want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("the invalid secret gave a code")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for _, tc := range []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	} {
+		code, err := Code(rfcSecret, current+tc.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step, ok := Validate(rfcSecret, code, now, 1)
+		if ok != tc.ok {
+			t.Errorf("step %+d: valid %v, want %v", tc.offset, ok, tc.ok)
+		}
+		// the matched step is what the replay check keeps
+		if ok && step != current+tc.offset {
+			t.Errorf("step %+d: matched step %d, want %d", tc.offset, step, current+tc.offset)
+		}
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("code %q is valid", code)
+		}
+	}
+}
+
+func TestValidateSpaces(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Validate(rfcSecret, " "+code[:3]+" "+code[3:]+" ", now, 0); !ok {
+		t.Error("the code typed with spaces is rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("recovery code %q is not xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+
+		// the user may type it in any case, without the dash or spaced
+		for _, typed := range []string{code, " " + code + " ", code[:5] + code[6:], "  " + code[:5] + " " + code[6:]} {
+			if NormalizeRecoveryCode(typed) != code {
+				t.Errorf("%q normalizes to %q, want %q", typed, NormalizeRecoveryCode(typed), code)
+			}
+		}
+	}
+}
+
+// testUser is a user of the postgres of db_url with the second factor
+// enabled at the step. Without db_url the test is skipped
+func testUser(t *testing.T, step int64) (*database.Queries, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+
+	dbURL := os.Getenv("db_url")
+	if dbURL == "" {
+		t.Skip("db_url is not set, skipping the postgres test")
+	}
+
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	q := database.New(conn)
+
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		Email:        "totp-" + uuid.NewString() + "@test.local",
+		PasswordHash: "-",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := q.DeleteUserByID(ctx, user.ID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := q.EnableUserTOTP(ctx, database.EnableUserTOTPParams{
+		ID:           user.ID,
+		TotpSecret:   rfcSecret,
+		TotpLastStep: step,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return q, user.ID
+}
+
+// the login keeps the last step used, the step is only taken once it's
+// past it
+func TestReplayedStepRejected(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	enrolled := Step(now) - 1
+
+	q, userID := testUser(t, enrolled)
+
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(rfcSecret, code, now, 1)
+	if !ok {
+		t.Fatal("the current code is rejected")
+	}
+
+	for i, want := range []int64{1, 0} {
+		n, err := q.UpdateUserTOTPStep(ctx, database.UpdateUserTOTPStepParams{
+			ID:           userID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("use %d of the step took %d rows, want %d", i+1, n, want)
+		}
+	}
+
+	// the code of the enrollment step, still in the window, is older
+	old, _ := Code(rfcSecret, enrolled)
+	if step, ok := Validate(rfcSecret, old, now, 1); ok {
+		n, err := q.UpdateUserTOTPStep(ctx, database.UpdateUserTOTPStepParams{
+			ID:           userID,
+			TotpLastStep: step,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Error("the step older than the last one was taken")
+		}
+	}
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	ctx := context.Background()
+	q, userID := testUser(t, 0)
+
+	codes, err := GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range codes {
+		if err := q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: tokens.Hash(code),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	typed := NormalizeRecoveryCode(codes[0][:5] + codes[0][6:])
+	for i, want := range []int64{1, 0} {
+		n, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: tokens.Hash(typed),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("use %d of the recovery code took %d rows, want %d", i+1, n, want)
+		}
+	}
+
+	unused, err := q.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unused != 1 {
+		t.Errorf("%d recovery codes left, want 1", unused)
+	}
+}
@@ -0,0 +1,39 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expire_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPIKeysByUserID :many
+SELECT * FROM api_keys
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetActiveAPIKeyByHash :one
+SELECT * FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL;
+
+-- name: UpdateAPIKeyLastUsed :exec
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (api_key_id, token_hash, expire_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetAPITokenWithKey :one
+SELECT t.expire_at, k.id AS api_key_id, k.user_id, k.scopes, k.expire_at AS key_expire_at
+FROM api_tokens AS t
+JOIN api_keys AS k
+        ON k.id = t.api_key_id
+WHERE t.token_hash = $1 AND k.revoked_at IS NULL;
+
+-- name: DeleteExpiredAPITokens :exec
+DELETE FROM api_tokens
+WHERE expire_at < $1;
@@ -0,0 +1,17 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (actor_id, actor_email, actor_role, ip, action, target_type, target_id, changes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: SearchAuditEvents :many
+SELECT * FROM audit_events
+WHERE action LIKE sqlc.arg(action)
+        AND actor_email LIKE sqlc.arg(actor_email)
+        AND (target_type LIKE sqlc.arg(target) OR target_id LIKE sqlc.arg(target))
+        AND created_at >= sqlc.arg(since)
+        AND created_at < sqlc.arg(until)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetAuditEventActions :many
+SELECT DISTINCT action FROM audit_events
+ORDER BY action;
@@ -20,18 +20,4 @@ WHERE name = $1;
 -- name: DecrementValueByName :exec
 UPDATE collection_meta
 SET value = (CAST(value as INTEGER)-1)::VARCHAR
-WHERE name = $1;
-
--- name: GetFreelistNim :one
-SELECT value FROM collection_meta
-WHERE name = 'freelist-nim'
-ORDER BY value ASC
-LIMIT 1;
-
--- name: DeleteFreelistNim :exec
-DELETE FROM collection_meta
-WHERE name = 'freelist-nim' AND value = $1;
-
--- name: AddToFreelist :exec
-INSERT INTO collection_meta (name, value)
-VALUES ('freelist-nim', $1);
\ No newline at end of file
+WHERE name = $1;
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- name: CreateCourse :one
+INSERT INTO courses (title, description, course_date, file_id, file_name, teacher_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetCourseById :one
+SELECT * FROM courses
+WHERE id = $1;
+
+-- name: GetCoursesByTeacherID :many
+SELECT * FROM courses
+WHERE teacher_id = $1
+ORDER BY is_archived ASC, course_date DESC;
+
+-- name: UpdateCourse :one
+UPDATE courses
+SET title = $2, description = $3, course_date = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ArchiveCourse :exec
+UPDATE courses
+SET is_archived = true, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateCourseFileStatus :exec
+UPDATE courses
+SET file_status = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetCoursesActive :many
+SELECT * FROM courses
+WHERE is_archived = FALSE
+ORDER BY course_date DESC;
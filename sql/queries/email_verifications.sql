@@ -0,0 +1,15 @@
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (user_id, token_hash, expire_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ConsumeEmailVerification :one
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expire_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidateEmailVerificationsByUserID :exec
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: EnrollStudent :exec
+INSERT INTO enrollments (student_id, course_id, study_plan_id, semester)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (student_id, course_id, semester)
+DO UPDATE SET status = 'active', updated_at = NOW();
+
+-- name: DropEnrollment :exec
+UPDATE enrollments
+SET status = 'dropped', updated_at = NOW()
+WHERE student_id = $1 AND course_id = $2 AND status = 'active';
+
+-- name: GetEnrollmentsByStudentID :many
+SELECT e.id, e.semester, e.status, e.created_at, e.course_id, c.title, c.file_status
+FROM enrollments AS e
+JOIN courses AS c
+        ON e.course_id = c.id
+WHERE e.student_id = $1
+ORDER BY e.semester DESC, e.created_at;
+
+-- name: CountActiveEnrollmentByUserID :one
+SELECT COUNT(*) FROM enrollments AS e
+JOIN students AS s
+        ON e.student_id = s.id
+WHERE s.user_id = $1 AND e.course_id = $2 AND e.status <> 'dropped';
+
+-- name: CompleteEnrollmentsByStudyPlan :exec
+UPDATE enrollments
+SET status = 'completed', updated_at = NOW()
+WHERE study_plan_id = $1 AND status = 'active';
@@ -0,0 +1,51 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (kind, payload, max_attempts)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ClaimNextJob :one
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_at = NOW(), updated_at = NOW()
+WHERE id = (
+    SELECT id FROM jobs
+    WHERE status = 'pending' AND run_at <= NOW()
+    ORDER BY run_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'done', locked_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', run_at = $2, last_error = $3, locked_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: BuryJob :exec
+UPDATE jobs
+SET status = 'dead', last_error = $2, locked_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RequeueDeadJob :execrows
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead';
+
+-- name: RequeueStaleJobs :exec
+UPDATE jobs
+SET status = 'pending', locked_at = NULL, updated_at = NOW()
+WHERE status = 'running' AND locked_at < $1;
+
+-- name: GetJobsLatest :many
+SELECT * FROM jobs
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: GetJobsCountByStatus :many
+SELECT status, COUNT(*) FROM jobs
+GROUP BY status
+ORDER BY status ASC;
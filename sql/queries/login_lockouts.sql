@@ -0,0 +1,30 @@
+-- name: GetLoginLockout :one
+SELECT * FROM login_lockouts
+WHERE user_id = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_lockouts (user_id, failed_count, last_failed_at)
+VALUES ($1, 1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET failed_count = CASE
+                WHEN login_lockouts.last_failed_at < sqlc.arg(window_start)::TIMESTAMP THEN 1
+                ELSE login_lockouts.failed_count + 1
+        END,
+        last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+-- name: LockLoginAccount :exec
+UPDATE login_lockouts
+SET locked_until = $2, lockout_count = lockout_count + 1, failed_count = 0
+WHERE user_id = $1;
+
+-- name: DeleteLoginLockout :exec
+DELETE FROM login_lockouts
+WHERE user_id = $1;
+
+-- name: GetLoginLockoutAll :many
+SELECT l.user_id, u.email, l.failed_count, l.lockout_count, l.last_failed_at, l.locked_until
+FROM login_lockouts AS l
+JOIN users AS u
+        ON u.id = l.user_id
+ORDER BY l.last_failed_at DESC;
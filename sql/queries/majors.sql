@@ -0,0 +1,26 @@
+-- name: CreateMajor :one
+INSERT INTO majors (name, code)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetMajorAll :many
+SELECT * FROM majors
+ORDER BY name;
+
+-- name: GetMajorById :one
+SELECT * FROM majors
+WHERE id = $1;
+
+-- name: GetMajorByName :one
+SELECT * FROM majors
+WHERE name = $1;
+
+-- name: DeleteMajorById :exec
+DELETE FROM majors
+WHERE id = $1;
+
+-- name: CountStudentsByMajor :one
+SELECT COUNT(*) FROM students AS s
+JOIN study_plans AS sp
+        ON s.study_plan_id = sp.id
+WHERE sp.major = $1;
@@ -0,0 +1,26 @@
+-- name: NextNimSerial :one
+INSERT INTO nim_sequences (year, major_code, last_serial)
+VALUES ($1, $2, 1)
+ON CONFLICT (year, major_code) DO UPDATE
+SET last_serial = nim_sequences.last_serial + 1,
+    updated_at = NOW()
+RETURNING last_serial;
+
+-- name: ClaimReleasedNim :one
+DELETE FROM nim_released
+WHERE nim = (
+        SELECT r.nim FROM nim_released AS r
+        WHERE r.year = $1 AND r.major_code = $2
+                AND r.released_at <= $3
+                AND r.nim ~ sqlc.arg(pattern)::TEXT
+                AND NOT EXISTS (SELECT 1 FROM students AS s WHERE s.nim = r.nim)
+        ORDER BY r.nim ASC
+        LIMIT 1
+        FOR UPDATE SKIP LOCKED
+)
+RETURNING nim;
+
+-- name: ReleaseNim :exec
+INSERT INTO nim_released (nim, year, major_code)
+VALUES ($1, $2, $3)
+ON CONFLICT (nim) DO NOTHING;
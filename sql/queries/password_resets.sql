@@ -0,0 +1,20 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (user_id, token_hash, expire_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetActivePasswordReset :one
+SELECT * FROM password_resets
+WHERE token_hash = $1 AND used_at IS NULL AND expire_at > NOW();
+
+-- name: ConsumePasswordReset :one
+UPDATE password_resets
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expire_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidatePasswordResetsByUserID :exec
+UPDATE password_resets
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
@@ -0,0 +1,29 @@
+-- name: GetRolesAll :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: GetRoleByName :one
+SELECT * FROM roles
+WHERE name = $1;
+
+-- name: CreateRole :one
+INSERT INTO roles (name, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteRole :execrows
+DELETE FROM roles
+WHERE name = $1 AND is_system = FALSE;
+
+-- name: GetRolePermissionsAll :many
+SELECT * FROM role_permissions
+ORDER BY role, permission;
+
+-- name: CreateRolePermission :one
+INSERT INTO role_permissions (role, permission)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteRolePermission :execrows
+DELETE FROM role_permissions
+WHERE role = $1 AND permission = $2;
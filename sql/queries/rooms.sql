@@ -7,3 +7,40 @@ WHERE name LIKE $1;
 -- name: GetStudentRoomById :one
 SELECT * FROM rooms
 WHERE id = $1;
+
+-- name: LockRoomsByMajor :many
+SELECT * FROM rooms
+WHERE major = $1
+ORDER BY name
+FOR UPDATE;
+
+-- name: GetLeastFullRoomByMajor :one
+SELECT r.* FROM rooms AS r
+WHERE r.major = $1
+        AND r.capacity > (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id)
+ORDER BY (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id), r.name
+LIMIT 1;
+
+-- name: GetRoomAll :many
+SELECT r.id, r.name, r.capacity, r.major,
+        (SELECT COUNT(*) FROM students AS s WHERE s.room_id = r.id)::INT AS students_count
+FROM rooms AS r
+ORDER BY r.major, r.name;
+
+-- name: CreateRoom :one
+INSERT INTO rooms (name, capacity, major)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateRoomCapacity :exec
+UPDATE rooms
+SET capacity = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteRoomById :exec
+DELETE FROM rooms
+WHERE id = $1;
+
+-- name: DeleteRoomsByMajor :exec
+DELETE FROM rooms
+WHERE major = $1;
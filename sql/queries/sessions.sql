@@ -1,15 +1,15 @@
 -- name: CreateUserSession :one
-INSERT INTO sessions (session_id, user_id, expire_at)
-VALUES ($1, $2, $3)
+INSERT INTO sessions (token_hash, user_id, expire_at, user_agent, ip_address)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: DeleteUserSession :exec
 DELETE FROM sessions
-WHERE session_id = $1;
+WHERE token_hash = $1;
 
 -- name: GetUserSession :one
 SELECT * FROM sessions
-WHERE session_id = $1;
+WHERE token_hash = $1;
 
 -- name: GetSessionIDAll :many
 SELECT id, last_activity FROM sessions;
@@ -17,7 +17,7 @@ SELECT id, last_activity FROM sessions;
 -- name: UpdateLastActivityUserSession :exec
 UPDATE sessions
 SET last_activity = NOW()
-WHERE session_id = $1;
+WHERE token_hash = $1;
 
 -- name: DeleteSessionByID :exec
 DELETE FROM sessions
@@ -25,9 +25,39 @@ WHERE id = $1;
 
 -- name: UpdateRevokeStatusUserSession :exec
 UPDATE sessions
-SET is_revoked = $1
-WHERE session_id = $1;
+SET is_revoked = $2
+WHERE token_hash = $1;
 
 -- name: CleanupRevokedSessions :exec
 DELETE FROM sessions
 WHERE is_revoked = true OR expire_at < NOW();
+
+-- name: GetActiveSessionsByUserID :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND is_revoked = FALSE AND expire_at > NOW()
+ORDER BY last_activity DESC;
+
+-- name: RevokeUserSessionByID :execrows
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE id = $1 AND user_id = $2;
+
+-- name: RevokeOtherUserSessions :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = $1 AND token_hash <> $2;
+
+-- name: RevokeUserSessionsByUserID :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = $1;
+
+-- name: RevokeUserSessionsByUserIDs :exec
+UPDATE sessions
+SET is_revoked = TRUE
+WHERE user_id = ANY(sqlc.arg(user_ids)::UUID[]);
+
+-- name: SweepUserSessions :exec
+DELETE FROM sessions
+WHERE is_revoked = TRUE OR expire_at < NOW()
+   OR last_activity < NOW() - sqlc.arg(idle_seconds)::INT * INTERVAL '1 second';
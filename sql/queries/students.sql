@@ -45,7 +45,7 @@ WHERE name LIKE $1 AND nim LIKE $2;
 
 -- name: GetStudentsByRoomAndMajor :many
 SELECT s.id, s.created_at, s.updated_at, s.name, s.email, s.nim, 
-s.phone_number, r.name as room, std.major 
+s.phone_number, r.name as room, std.major, s.user_id
 FROM students as s
 JOIN rooms as r
         ON s.room_id = r.id
@@ -6,3 +6,57 @@ WHERE semester = $1 AND major = $2;
 -- name: GetStudyPlanById :one
 SELECT * FROM study_plans
 WHERE id = $1;
+
+-- name: GetStudyPlanAll :many
+SELECT * FROM study_plans
+ORDER BY major, semester;
+
+-- name: GetStudyPlanStudentsCount :many
+SELECT study_plan_id, COUNT(*) AS count
+FROM students
+GROUP BY study_plan_id;
+
+-- name: AddCourseToStudyPlan :exec
+INSERT INTO study_plan_courses (study_plan_id, course_id)
+VALUES ($1, $2)
+ON CONFLICT (study_plan_id, course_id) DO NOTHING;
+
+-- name: RemoveCourseFromStudyPlan :exec
+DELETE FROM study_plan_courses
+WHERE study_plan_id = $1 AND course_id = $2;
+
+-- name: GetStudyPlanCourses :many
+SELECT spc.study_plan_id, c.id, c.title, c.course_date, c.file_status, c.is_archived
+FROM study_plan_courses AS spc
+JOIN courses AS c
+        ON spc.course_id = c.id
+ORDER BY c.course_date DESC;
+
+-- name: GetCoursesByStudyPlanID :many
+SELECT c.* FROM courses AS c
+JOIN study_plan_courses AS spc
+        ON spc.course_id = c.id
+WHERE spc.study_plan_id = $1 AND c.is_archived = FALSE
+ORDER BY c.course_date DESC;
+
+-- name: CountStudyPlanCourse :one
+SELECT COUNT(*) FROM study_plan_courses
+WHERE study_plan_id = $1 AND course_id = $2;
+
+-- name: PromoteStudentsStudyPlan :execrows
+UPDATE students
+SET study_plan_id = sqlc.arg(next_study_plan_id), updated_at = NOW()
+WHERE study_plan_id = sqlc.arg(study_plan_id);
+
+-- name: LockStudyPlanById :one
+SELECT * FROM study_plans
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CreateStudyPlan :exec
+INSERT INTO study_plans (semester, major)
+VALUES ($1, $2);
+
+-- name: DeleteStudyPlansByMajor :exec
+DELETE FROM study_plans
+WHERE major = $1;
@@ -0,0 +1,36 @@
+-- name: CreateTeacher :one
+INSERT INTO
+teachers (
+        nip,
+        name,
+        email,
+        phone_number,
+        date_of_birth,
+        subjects,
+        user_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetTeacherAll :many
+SELECT * FROM teachers
+ORDER BY updated_at DESC;
+
+-- name: GetTeacherByUserId :one
+SELECT * FROM teachers
+WHERE user_id = $1;
+
+-- name: GetTeacherByNameOrNip :many
+SELECT * FROM teachers
+WHERE name LIKE $1 AND nip LIKE $2;
+
+-- name: UpdateTeacher :one
+UPDATE teachers
+SET phone_number = $2, subjects = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteTeacherByUserId :one
+DELETE FROM teachers
+WHERE user_id = $1
+RETURNING *;
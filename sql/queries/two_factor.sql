@@ -0,0 +1,44 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodesByUserID :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: GetRolePolicyAll :many
+SELECT * FROM role_policies
+ORDER BY role;
+
+-- name: CountTOTPRequiredRoles :one
+SELECT COUNT(*) FROM role_policies
+WHERE require_totp = TRUE AND role = ANY(sqlc.arg(roles)::VARCHAR[]);
+
+-- name: UpdateRolePolicyTOTP :exec
+UPDATE role_policies
+SET require_totp = $2, updated_at = NOW()
+WHERE role = $1;
+
+-- name: GetUserIDsOfRoleWithoutTOTP :many
+SELECT u.id FROM users AS u
+JOIN user_roles AS r
+        ON r.user_id = u.id
+WHERE r.role = $1 AND u.totp_enabled = FALSE;
+
+-- name: CreateRolePolicy :exec
+INSERT INTO role_policies (role)
+VALUES ($1)
+ON CONFLICT (role) DO NOTHING;
+
+-- name: DeleteRolePolicy :exec
+DELETE FROM role_policies
+WHERE role = $1;
@@ -0,0 +1,13 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, issuer, subject)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE issuer = $1 AND subject = $2;
+
+-- name: UpdateUserIdentityLastLogin :exec
+UPDATE user_identities
+SET last_login_at = CURRENT_TIMESTAMP
+WHERE id = $1;
@@ -7,3 +7,14 @@ RETURNING *;
 SELECT * FROM user_roles
 WHERE user_id = $1
 ORDER BY role ASC;
+
+-- name: CountUsersByRole :one
+SELECT COUNT(*) FROM user_roles
+WHERE role = $1;
+
+-- name: LockAdminBootstrap :exec
+SELECT pg_advisory_xact_lock(hashtext('admin_bootstrap'));
+
+-- name: DeleteUserRole :exec
+DELETE FROM user_roles
+WHERE user_id = $1 AND role = $2;
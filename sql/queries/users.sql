@@ -15,7 +15,7 @@ WHERE email = $1;
 SELECT * FROM users;
 
 -- name: GetUsersAllJoinRoles :many
-SELECT u.id, u.email, r.role, u.created_at
+SELECT u.id, u.email, r.role, u.created_at, u.email_verified_at
 FROM users AS u
 JOIN user_roles as r
   ON u.id = r.user_id;
@@ -23,3 +23,38 @@ JOIN user_roles as r
 -- name: DeleteUserByID :exec
 DELETE FROM users
 WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, password_change_required = FALSE, password_changed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET password_hash = sqlc.arg(password_hash)
+WHERE id = sqlc.arg(id) AND password_hash = sqlc.arg(verified_hash);
+
+-- name: RequireUserPasswordChange :execrows
+UPDATE users
+SET password_change_required = TRUE, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = TRUE, totp_last_step = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = '', totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE courses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    title VARCHAR(128) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    course_date DATE NOT NULL,
+    file_id VARCHAR(255) UNIQUE NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    teacher_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_archived BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- +goose Down
+DROP TABLE courses;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    kind VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'done', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX jobs_status_run_at_idx ON jobs (status, run_at);
+
+ALTER TABLE courses
+ADD COLUMN file_status VARCHAR(16) NOT NULL DEFAULT 'staging'
+    CHECK (file_status IN ('staging', 'ready', 'rejected'));
+
+-- +goose Down
+ALTER TABLE courses DROP file_status;
+DROP TABLE jobs;
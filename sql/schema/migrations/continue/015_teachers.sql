@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE teachers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    nip VARCHAR(24) UNIQUE NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    email VARCHAR(64) UNIQUE NOT NULL,
+    phone_number VARCHAR(20) NOT NULL CHECK (phone_number ~ '^\+?[0-9]{8,15}$'),
+    date_of_birth DATE NOT NULL,
+    subjects VARCHAR(255) NOT NULL DEFAULT '',
+    user_id UUID UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO collection_meta (name) VALUES ('teacher-coll');
+
+-- +goose Down
+DELETE FROM collection_meta WHERE name = 'teacher-coll';
+DROP TABLE teachers;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE study_plan_courses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    study_plan_id UUID NOT NULL REFERENCES study_plans(id) ON DELETE CASCADE,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    UNIQUE (study_plan_id, course_id)
+);
+
+CREATE TABLE enrollments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    study_plan_id UUID NOT NULL REFERENCES study_plans(id),
+    semester INT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'completed', 'dropped')),
+    UNIQUE (student_id, course_id, semester)
+);
+
+CREATE INDEX enrollments_study_plan_status_idx ON enrollments (study_plan_id, status);
+
+-- +goose Down
+DROP TABLE enrollments;
+DROP TABLE study_plan_courses;
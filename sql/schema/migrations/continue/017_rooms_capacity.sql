@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE rooms
+    ADD COLUMN capacity INT NOT NULL DEFAULT 30 CHECK (capacity >= 0),
+    ADD COLUMN major VARCHAR(64) NOT NULL DEFAULT '';
+
+UPDATE rooms SET major = 'TEKNIK INFORMATIKA' WHERE name LIKE 'TI%';
+UPDATE rooms SET major = 'REKAYASA PERANGKAT LUNAK' WHERE name LIKE 'RPL%';
+UPDATE rooms SET major = 'AKUNTANSI' WHERE name LIKE 'AK%';
+
+CREATE INDEX rooms_major_idx ON rooms (major);
+
+-- +goose Down
+DROP INDEX rooms_major_idx;
+ALTER TABLE rooms
+    DROP COLUMN capacity,
+    DROP COLUMN major;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE majors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(64) UNIQUE NOT NULL,
+    code VARCHAR(8) UNIQUE NOT NULL
+);
+
+INSERT INTO majors (name, code) VALUES
+    ('TEKNIK INFORMATIKA', 'TI'),
+    ('REKAYASA PERANGKAT LUNAK', 'RPL'),
+    ('AKUNTANSI', 'AK');
+
+-- +goose Down
+DROP TABLE majors;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE nim_sequences (
+    year INT NOT NULL,
+    major_code VARCHAR(8) NOT NULL,
+    last_serial INT NOT NULL DEFAULT 0 CHECK (last_serial >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (year, major_code)
+);
+
+CREATE TABLE nim_released (
+    nim VARCHAR(20) PRIMARY KEY,
+    year INT NOT NULL,
+    major_code VARCHAR(8) NOT NULL,
+    released_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX nim_released_cohort_idx ON nim_released (year, major_code, nim);
+
+-- the old counter & freelist lived in collection_meta as VARCHAR
+DELETE FROM collection_meta WHERE name IN ('student-nim', 'freelist-nim');
+
+-- +goose Down
+DROP INDEX nim_released_cohort_idx;
+DROP TABLE nim_released;
+DROP TABLE nim_sequences;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- classrooms used to live in predefined_tables.sql, but it references
+-- students, so it can only be created after 001
+CREATE TABLE IF NOT EXISTS classrooms (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    room_id UUID REFERENCES rooms(id) ON DELETE CASCADE NOT NULL,
+    student_id UUID REFERENCES students(id) ON DELETE CASCADE NOT NULL
+);
+
+-- +goose Down
+DROP TABLE classrooms;
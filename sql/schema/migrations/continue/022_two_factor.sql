@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN totp_secret VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE role_policies (
+    role VARCHAR(64) PRIMARY KEY,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    require_totp BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO role_policies (role) VALUES ('admin'), ('teacher'), ('student');
+
+-- +goose Down
+DROP TABLE role_policies;
+DROP TABLE recovery_codes;
+ALTER TABLE users
+    DROP COLUMN totp_secret,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_last_step;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE sessions
+    ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN ip_address VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE sessions
+    DROP COLUMN user_agent,
+    DROP COLUMN ip_address;
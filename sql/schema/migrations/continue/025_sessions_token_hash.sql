@@ -0,0 +1,10 @@
+-- +goose Up
+-- the old ids were guessable from the user id & the login time,
+-- every user logs in again to get a random token
+DELETE FROM sessions;
+ALTER TABLE sessions RENAME COLUMN session_id TO token_hash;
+ALTER TABLE sessions ALTER COLUMN token_hash TYPE VARCHAR(64);
+
+-- +goose Down
+ALTER TABLE sessions ALTER COLUMN token_hash TYPE VARCHAR(255);
+ALTER TABLE sessions RENAME COLUMN token_hash TO session_id;
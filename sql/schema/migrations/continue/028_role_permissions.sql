@@ -0,0 +1,51 @@
+-- +goose Up
+CREATE TABLE roles (
+    name VARCHAR(64) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    -- the roles the code itself logs in as, they can't be deleted
+    is_system BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE role_permissions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(128) NOT NULL,
+    UNIQUE (role, permission)
+);
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('admin', 'Manages the school data & the accounts', TRUE),
+    ('teacher', 'Teaches the courses', TRUE),
+    ('student', 'Enrolls in the courses of the study plan', TRUE);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'adminPanelPages:view'),
+    ('admin', 'users:*'),
+    ('admin', 'teachers:*'),
+    ('admin', 'students:*'),
+    ('admin', 'studentCreatePage:view'),
+    ('admin', 'studyPlans:*'),
+    ('admin', 'majors:*'),
+    ('admin', 'rooms:*'),
+    ('admin', 'security:*'),
+    ('admin', 'sessions:*'),
+    ('admin', 'permissions:*'),
+    ('admin', 'account:*'),
+    ('teacher', 'homePage:view'),
+    ('teacher', 'coursePage:view'),
+    ('teacher', 'teachers:view'),
+    ('teacher', 'courses:*'),
+    ('teacher', 'account:*'),
+    ('student', 'homePage:view'),
+    ('student', 'students:view'),
+    ('student', 'courses:view'),
+    ('student', 'studyPlan:view'),
+    ('student', 'enrollments:create'),
+    ('student', 'enrollments:delete'),
+    ('student', 'account:*');
+
+-- +goose Down
+DROP TABLE role_permissions;
+DROP TABLE roles;
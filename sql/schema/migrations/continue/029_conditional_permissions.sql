@@ -0,0 +1,30 @@
+-- +goose Up
+-- the ownership checks the handlers did by hand are the :own conditions now
+UPDATE role_permissions SET permission = 'students:view:own'
+WHERE role = 'student' AND permission = 'students:view';
+
+UPDATE role_permissions SET permission = 'teachers:view:own'
+WHERE role = 'teacher' AND permission = 'teachers:view';
+
+DELETE FROM role_permissions
+WHERE role = 'teacher' AND permission = 'courses:*';
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('teacher', 'courses:view'),
+    ('teacher', 'courses:create'),
+    ('teacher', 'courses:update:own'),
+    ('teacher', 'courses:archive:own')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- +goose Down
+DELETE FROM role_permissions
+WHERE role = 'teacher' AND permission IN ('courses:view', 'courses:create', 'courses:update:own', 'courses:archive:own');
+
+INSERT INTO role_permissions (role, permission) VALUES ('teacher', 'courses:*')
+ON CONFLICT (role, permission) DO NOTHING;
+
+UPDATE role_permissions SET permission = 'teachers:view'
+WHERE role = 'teacher' AND permission = 'teachers:view:own';
+
+UPDATE role_permissions SET permission = 'students:view'
+WHERE role = 'student' AND permission = 'students:view:own';
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- the actor is kept by email too, the user may be deleted later on
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    actor_role VARCHAR(64) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(64) NOT NULL,
+    target_id VARCHAR(128) NOT NULL DEFAULT '',
+    -- {"field": {"before": ..., "after": ...}} of the changed fields only
+    changes JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at DESC);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'audit:view');
+
+-- +goose Down
+DELETE FROM role_permissions WHERE permission = 'audit:view';
+DROP TABLE audit_events;
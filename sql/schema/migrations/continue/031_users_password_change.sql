@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users
+    -- set by the admin, the next login has to pick a new password first
+    ADD COLUMN password_change_required BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN password_changed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN password_changed_at,
+    DROP COLUMN password_change_required;
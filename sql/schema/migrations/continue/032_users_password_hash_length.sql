@@ -0,0 +1,7 @@
+-- +goose Up
+-- the hashes carry their algorithm & parameters now (argon2id is ~100 chars)
+ALTER TABLE users ALTER COLUMN password_hash TYPE VARCHAR(255);
+
+-- the down keeps the width: the argon2id hashes don't fit VARCHAR(64),
+-- narrowing it back would fail (or cut them) once any user logged in
+-- +goose Down
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users
+    -- NULL until the link mailed to the address is opened, the login
+    -- refuses the account meanwhile
+    ADD COLUMN email_verified_at TIMESTAMP;
+
+-- the accounts made so far keep logging in
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expire_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
+
+-- +goose Down
+DROP TABLE email_verifications;
+
+ALTER TABLE users
+    DROP COLUMN email_verified_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- the file of the course was checked by hand, the teacher who owns it or
+-- the student enrolled to it. The same as the policies now
+INSERT INTO role_permissions (role, permission) VALUES
+    ('teacher', 'courses:download:own'),
+    ('student', 'courses:download:enrolled')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- +goose Down
+DELETE FROM role_permissions
+WHERE (role = 'teacher' AND permission = 'courses:download:own')
+    OR (role = 'student' AND permission = 'courses:download:enrolled');
@@ -0,0 +1,9 @@
+-- +goose Up
+-- the student updates the own profile, the handler checked nothing before
+INSERT INTO role_permissions (role, permission) VALUES
+    ('student', 'students:update:own')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- +goose Down
+DELETE FROM role_permissions
+WHERE role = 'student' AND permission = 'students:update:own';
@@ -0,0 +1,9 @@
+-- +goose Up
+-- the requeue of the dead jobs changes them, viewing the panel isn't enough
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'jobs:update')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- +goose Down
+DELETE FROM role_permissions
+WHERE role = 'admin' AND permission = 'jobs:update';
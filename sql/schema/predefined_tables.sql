@@ -1,5 +1,8 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
 CREATE TABLE study_plans (
-    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     semester INT NOT NULL,
@@ -7,17 +10,12 @@ CREATE TABLE study_plans (
 );
 
 CREATE TABLE rooms (
-    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     name VARCHAR(64) NOT NULL
 );
 
-
-CREATE TABLE classrooms (
-    id UUID NOT NULL DEFAULT uuid_generate_v4(),
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    room_id UUID REFERENCES rooms(id) ON DELETE CASCADE NOT NULL,
-    student_id UUID REFERENCES students(id) ON DELETE CASCADE NOT NULL
-);
+-- +goose Down
+DROP TABLE rooms;
+DROP TABLE study_plans;
@@ -0,0 +1,8 @@
+// Package schema embeds the goose annotated schema files, so the binaries
+// can migrate the database without the sql directory around
+package schema
+
+import "embed"
+
+//go:embed predefined_tables.sql migrations/*.sql migrations/continue/*.sql
+var FS embed.FS
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/server"
+)
+
+const (
+	AUDIT_STUDENT_CREATE           = "student.create"
+	AUDIT_STUDENT_UPDATE           = "student.update"
+	AUDIT_STUDENT_DELETE           = "student.delete"
+	AUDIT_TEACHER_CREATE           = "teacher.create"
+	AUDIT_TEACHER_DELETE           = "teacher.delete"
+	AUDIT_USER_CREATE              = "user.create"
+	AUDIT_MAJOR_CREATE             = "major.create"
+	AUDIT_MAJOR_DELETE             = "major.delete"
+	AUDIT_ROOM_CREATE              = "room.create"
+	AUDIT_ROOM_UPDATE              = "room.update"
+	AUDIT_ROOM_DELETE              = "room.delete"
+	AUDIT_COURSE_CREATE            = "course.create"
+	AUDIT_COURSE_UPDATE            = "course.update"
+	AUDIT_COURSE_ARCHIVE           = "course.archive"
+	AUDIT_STUDY_PLAN_ADD_COURSE    = "studyPlan.addCourse"
+	AUDIT_STUDY_PLAN_REMOVE_COURSE = "studyPlan.removeCourse"
+	AUDIT_STUDY_PLAN_PROMOTE       = "studyPlan.promote"
+	AUDIT_ENROLLMENT_CREATE        = "enrollment.create"
+	AUDIT_ENROLLMENT_DROP          = "enrollment.drop"
+	AUDIT_ROLE_CREATE              = "role.create"
+	AUDIT_ROLE_DELETE              = "role.delete"
+	AUDIT_ROLE_PERMISSION_CREATE   = "rolePermission.create"
+	AUDIT_ROLE_PERMISSION_DELETE   = "rolePermission.delete"
+	AUDIT_TWO_FACTOR_POLICY_UPDATE = "twoFactorPolicy.update"
+	AUDIT_LOGIN_UNLOCK             = "login.unlock"
+	AUDIT_PASSWORD_CHANGE          = "password.change"
+	AUDIT_PASSWORD_REQUIRE_CHANGE  = "password.requireChange"
+	AUDIT_IMPERSONATION_START      = "impersonation.start"
+	AUDIT_IMPERSONATION_STOP       = "impersonation.stop"
+	AUDIT_IMPERSONATION_REQUEST    = "impersonation.request"
+	AUDIT_EMAIL_VERIFY             = "email.verify"
+	AUDIT_PASSWORD_RESET           = "password.reset"
+	AUDIT_SESSION_REVOKE           = "session.revoke"
+	AUDIT_SESSION_REVOKE_ALL       = "session.revokeAll"
+	AUDIT_JOB_REQUEUE              = "job.requeue"
+	AUDIT_API_KEY_CREATE           = "apiKey.create"
+	AUDIT_API_KEY_REVOKE           = "apiKey.revoke"
+	AUDIT_TWO_FACTOR_ENABLE        = "twoFactor.enable"
+	AUDIT_TWO_FACTOR_DISABLE       = "twoFactor.disable"
+
+	AUDIT_PAGE_SIZE = 200
+)
+
+// the fields never written to the log, matched against the lowercased
+// json field name
+var auditRedacted = []string{"password", "hash", "secret", "token"}
+
+// AuditEvent is one data change, Before is nil on the creation and After
+// is nil on the deletion. The records are diffed field by field, only the
+// changed ones land in the log
+type AuditEvent struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+}
+
+// Audit writes the event with the actor of the request, qtx is the
+// transaction of the change itself so the event is only kept along with it
+func Audit(c echo.Context, qtx *database.Queries, event AuditEvent) error {
+	ctx := c.Request().Context()
+
+	changes, err := auditChanges(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+
+	params := database.CreateAuditEventParams{
+		Ip:         c.RealIP(),
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Changes:    changes,
+	}
+
+	// the unauthenticated requests (password reset...) have no actor
+	if actorID, actorRole := auditActor(c); actorID != uuid.Nil {
+		user, err := qtx.GetUserById(ctx, actorID)
+		if err != nil {
+			return err
+		}
+
+		params.ActorID = uuid.NullUUID{UUID: actorID, Valid: true}
+		params.ActorEmail = user.Email
+		params.ActorRole = actorRole
+	}
+
+	return qtx.CreateAuditEvent(ctx, params)
+}
+
+// auditActor is the user behind the request. While an admin views the
+// app as a student it's the admin, the student is only the target
+func auditActor(c echo.Context) (uuid.UUID, string) {
+	if impersonator, ok := c.Get("impersonator_id").(uuid.UUID); ok {
+		role, _ := c.Get("impersonator_role").(string)
+		return impersonator, role
+	}
+
+	claims, ok := c.Get("claims").(*server.Claims)
+	if !ok {
+		return uuid.Nil, ""
+	}
+
+	if claims.ActiveRole == "" {
+		return claims.UserID, strings.Join(claims.Roles, ",")
+	}
+	return claims.UserID, claims.ActiveRole
+}
+
+func auditChanges(before, after any) (json.RawMessage, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	type change struct {
+		Before any `json:"before,omitempty"`
+		After  any `json:"after,omitempty"`
+	}
+
+	changes := map[string]change{}
+	for name, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[name]) {
+			changes[name] = change{Before: value, After: afterFields[name]}
+		}
+	}
+
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = change{After: value}
+		}
+	}
+
+	return json.Marshal(changes)
+}
+
+// auditFields is the record as a flat json object, without the redacted
+// fields
+func auditFields(record any) (map[string]any, error) {
+	fields := map[string]any{}
+	if record == nil {
+		return fields, nil
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		lower := strings.ToLower(name)
+		for _, redacted := range auditRedacted {
+			if strings.Contains(lower, redacted) {
+				delete(fields, name)
+				break
+			}
+		}
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+)
+
+const catalogTTL = 5 * time.Minute
+
+// Catalog is the cached lookup of the majors & rooms names, used by the
+// oneof_major/oneof_room validators and the filter dropdowns. The admin
+// handlers call Invalidate after every change, the ttl covers the other
+// process (webserver & apiserver don't share the memory)
+type Catalog struct {
+	queries *database.Queries
+
+	mu       sync.RWMutex
+	majors   []string
+	rooms    []string
+	expireAt time.Time
+	// generation is bumped by Invalidate, a load that started before it
+	// read the tables before the change and is thrown away
+	generation uint64
+}
+
+func NewCatalog(queries *database.Queries) *Catalog {
+	return &Catalog{queries: queries}
+}
+
+func (c *Catalog) Majors(ctx context.Context) ([]string, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.majors, nil
+}
+
+func (c *Catalog) Rooms(ctx context.Context) ([]string, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rooms, nil
+}
+
+func (c *Catalog) HasMajor(ctx context.Context, major string) bool {
+	majors, err := c.Majors(ctx)
+	return err == nil && slices.Contains(majors, major)
+}
+
+func (c *Catalog) HasRoom(ctx context.Context, room string) bool {
+	rooms, err := c.Rooms(ctx)
+	return err == nil && slices.Contains(rooms, room)
+}
+
+func (c *Catalog) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expireAt = time.Time{}
+	c.generation++
+}
+
+func (c *Catalog) load(ctx context.Context) error {
+	for {
+		c.mu.RLock()
+		fresh := time.Now().Before(c.expireAt)
+		generation := c.generation
+		c.mu.RUnlock()
+
+		if fresh {
+			return nil
+		}
+
+		majors, err := c.queries.GetMajorAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		rooms, err := c.queries.GetRoomAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		majorNames := make([]string, len(majors))
+		for i, m := range majors {
+			majorNames[i] = m.Name
+		}
+
+		roomNames := make([]string, len(rooms))
+		for i, r := range rooms {
+			roomNames[i] = r.Name
+		}
+
+		c.mu.Lock()
+		stale := c.generation != generation
+		if !stale {
+			c.majors = majorNames
+			c.rooms = roomNames
+			c.expireAt = time.Now().Add(catalogTTL)
+		}
+		c.mu.Unlock()
+
+		// invalidated while loading, the change may be missing: load again
+		if !stale {
+			return nil
+		}
+	}
+}
@@ -10,12 +10,6 @@ import (
 	"time"
 
 	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
-	"golang.org/x/crypto/bcrypt"
-)
-
-var (
-	MAJOR = []string{"TEKNIK INFORMATIKA", "REKAYASA PERANGKAT LUNAK", "AKUNTANSI"}
-	ROOM  = []string{"TIR1", "TIR2", "RPLR1", "RPLR2", "AKR1", "AKR2"}
 )
 
 const (
@@ -24,6 +18,28 @@ const (
 	USER_ROLE_TEACHER   = "teacher"
 	USER_ROLE_SUPERUSER = "superuser"
 
+	ENROLLMENT_STATUS_ACTIVE    = "active"
+	ENROLLMENT_STATUS_COMPLETED = "completed"
+	ENROLLMENT_STATUS_DROPPED   = "dropped"
+
+	STUDY_PLAN_SEMESTERS = 8
+
+	PASSWORD_RESET_TOKEN_TTL = 30 * time.Minute
+
+	EMAIL_VERIFICATION_TOKEN_TTL = 48 * time.Hour
+
+	TOTP_ISSUER            = "RambanBelajar"
+	TOTP_SKEW              = 1
+	TWO_FACTOR_PENDING_TTL = 5 * time.Minute
+	RECOVERY_CODES_COUNT   = 10
+
+	SESSION_TTL            = 24 * time.Hour
+	SESSION_USER_AGENT_MAX = 512
+
+	API_TOKEN_TTL = 15 * time.Minute
+
+	IMPERSONATION_TTL = 15 * time.Minute
+
 	// error message
 	ERROR_USER_UNAUTHENTICATED     = "You're Not Authenticated, Cannot Access !!!"
 	ERROR_USER_UNAUTHORIZED        = "Permission Denied: user unauthorized, not allowed to access"
@@ -31,6 +47,45 @@ const (
 	ERROR_INVALID_NIP              = "error: invalid nomer induk pengguna (nip), please check your birthdate/nip"
 	ERROR_INVALID_CONFIRM_PASSWORD = "error: your confirmation password is invalid"
 	ERROR_INVALID_INPUT_DATA       = "error: invalid input data. please check again and follow the proper data format"
+	ERROR_COURSE_NOT_IN_STUDY_PLAN = "error: the course is not part of your current study plan"
+	ERROR_MAJOR_HAS_STUDENTS       = "error: the major still has students, cannot be deleted"
+	ERROR_INVALID_RESET_TOKEN      = "error: the reset link is invalid or expired, please request a new one"
+	ERROR_INVALID_TOTP_CODE        = "error: invalid authentication code, please try again"
+	ERROR_TWO_FACTOR_EXPIRED       = "error: the login step expired, please login again"
+	ERROR_TWO_FACTOR_REQUIRED      = "error: two factor authentication is required for your role, it cannot be disabled"
+	ERROR_ACCOUNT_LOCKED           = "error: too many failed login attempts, the account is temporarily locked. try again later or contact the admin"
+	ERROR_SESSION_NOT_FOUND        = "error: the session is not found or already ended"
+	ERROR_API_KEY_NOT_FOUND        = "error: the api key is not found or already revoked"
+	ERROR_SSO_FAILED               = "error: the single sign-on failed, please try again or login with your password"
+	ERROR_SSO_EXPIRED              = "error: the single sign-on step expired, please try again"
+	ERROR_SSO_UNVERIFIED_EMAIL     = "error: your email is not verified by the identity provider"
+	ERROR_SSO_NO_ACCOUNT           = "error: no account uses the email of your identity provider, contact the admin"
+	ERROR_SSO_NO_ROLE              = "error: your groups at the identity provider give no role in the app, contact the admin"
+	ERROR_INVALID_PERMISSION       = "error: the permission must be * or resource:action with an optional :own, :room, :major or :enrolled, only the action may be *"
+	ERROR_PERMISSION_NOT_FOUND     = "error: the role doesn't have the permission"
+	ERROR_PERMISSION_EXISTS        = "error: the role already has the permission"
+	ERROR_PERMISSION_NOT_HELD      = "error: you can only grant what your own role is allowed to do"
+	ERROR_ROLE_NOT_FOUND           = "error: the role is not found"
+	ERROR_ROLE_EXISTS              = "error: the role already exists"
+	ERROR_ROLE_IN_USE              = "error: the role is still held by users, cannot be deleted"
+	ERROR_ROLE_NOT_DELETABLE       = "error: the role is a system role, cannot be deleted"
+	ERROR_PERMISSION_LOCKOUT       = "error: the change would lock your role out of the permission editor"
+	ERROR_WRONG_CURRENT_PASSWORD   = "error: the current password is wrong"
+	ERROR_PASSWORD_UNCHANGED       = "error: the new password must be different from the current one"
+	ERROR_USER_NOT_FOUND           = "error: the user is not found"
+	ERROR_STUDENT_NOT_FOUND        = "error: the student is not found"
+	ERROR_JOB_NOT_DEAD             = "error: the job is not found or not dead, only the dead jobs can be requeued"
+	ERROR_IMPERSONATION_TARGET     = "error: only the student accounts can be viewed as, not your own nor an admin's"
+	ERROR_IMPERSONATION_READ_ONLY  = "Permission Denied: viewing as a student is read only, stop the view to make changes"
+	ERROR_NOT_IMPERSONATING        = "error: you're not viewing as a student"
+	ERROR_EMAIL_NOT_VERIFIED       = "error: the email of the account is not verified yet, open the link we mailed you"
+	ERROR_INVALID_VERIFY_TOKEN     = "error: the verification link is invalid or expired, please login to get a new one"
+
+	// info message
+	INFO_PASSWORD_RESET_SENT      = "If the email belongs to an account, a reset link is on its way. The link expires in 30 minutes"
+	INFO_PASSWORD_CHANGE_REQUIRED = "Your password has to be changed before continuing, the admin asked for a new one"
+	INFO_VERIFICATION_SENT        = "If the account still needs it, a verification link is on its way. The link expires in 48 hours"
+	INFO_EMAIL_VERIFIED           = "Your email is verified, you can login now"
 )
 
 type dbFunc = func(q *database.Queries) error
@@ -107,19 +162,6 @@ func ValidationErrorMsg(errMsg string) string {
 	return errMsg
 }
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14) // Use cost 14 for strong security
-	if err != nil {
-		return "", err
-	}
-	return string(bytes), nil
-}
-
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
 func InternalServerErrorMessage(debugCode, errMsg string) string {
 	return fmt.Sprintf("500 Internal Server Error; Please Contact Support with CODE:%s. \n%v", debugCode, errMsg)
 }
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"regexp"
 	"slices"
 	"strings"
@@ -24,7 +25,8 @@ func (cv *CustomValidator) Validate(i any) error {
 	return nil
 }
 
-func NewCustomValidator() *CustomValidator {
+// NewCustomValidator takes the catalog for the majors & rooms lookup
+func NewCustomValidator(catalog *Catalog) *CustomValidator {
 	v := validator.New()
 
 	v.RegisterValidation("name_constraints", func(fl validator.FieldLevel) bool {
@@ -34,12 +36,12 @@ func NewCustomValidator() *CustomValidator {
 
 	v.RegisterValidation("oneof_major", func(fl validator.FieldLevel) bool {
 		majorStr := fl.Field().String()
-		return slices.Contains(MAJOR, majorStr)
+		return catalog.HasMajor(context.Background(), majorStr)
 	})
 
 	v.RegisterValidation("oneof_room", func(fl validator.FieldLevel) bool {
 		roomStr := fl.Field().String()
-		return slices.Contains(ROOM, roomStr)
+		return catalog.HasRoom(context.Background(), roomStr)
 	})
 
 	v.RegisterValidation("nochars", func(fl validator.FieldLevel) bool {
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/database"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/mailer"
+	"github.com/muhamadagilf/rambanbelajar_gohtmx/internal/tokens"
+)
+
+// AppURL is the base of the links that leave the app (emails), app_url
+// wins over the request host, which the client controls
+func AppURL(c echo.Context) string {
+	if base := os.Getenv("app_url"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+// NewEmailVerification replaces the pending link of the user, qtx is the
+// transaction the account is created in. The login refuses the account
+// until the mailed link is opened. The token only goes out by mail, the
+// table keeps its hash
+func NewEmailVerification(ctx context.Context, qtx *database.Queries, userID uuid.UUID) (string, error) {
+	token, tokenHash, err := tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	// only the latest link works
+	if err := qtx.InvalidateEmailVerificationsByUserID(ctx, userID); err != nil {
+		return "", err
+	}
+
+	_, err = qtx.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpireAt:  time.Now().Add(EMAIL_VERIFICATION_TOKEN_TTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// SendEmailVerification is sent once the account is committed, baseURL is
+// AppURL
+func SendEmailVerification(ctx context.Context, m mailer.Mailer, baseURL, email, token string) error {
+	link := baseURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return m.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your RambanBelajar email",
+		Body: fmt.Sprintf(
+			"A RambanBelajar account was created with this email.\n\n"+
+				"Open the link below to verify it, the login works after that. "+
+				"The link expires in %v:\n%s\n\n"+
+				"If you don't know about the account, simply ignore this email.\n",
+			EMAIL_VERIFICATION_TOKEN_TTL, link,
+		),
+	})
+}
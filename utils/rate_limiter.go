@@ -35,6 +35,46 @@ var (
 			TokenCapacity: 3.0,
 		},
 
+		"POST /forgot-password": {
+			RateLimit:     3.0 / 60.0,
+			TokenCapacity: 3.0,
+		},
+
+		"POST /admin/forgot-password": {
+			RateLimit:     3.0 / 60.0,
+			TokenCapacity: 3.0,
+		},
+
+		"POST /verify-email/resend": {
+			RateLimit:     3.0 / 60.0,
+			TokenCapacity: 3.0,
+		},
+
+		"POST /reset-password": {
+			RateLimit:     5.0 / 60.0,
+			TokenCapacity: 5.0,
+		},
+
+		"POST /login/2fa": {
+			RateLimit:     5.0 / 60.0,
+			TokenCapacity: 5.0,
+		},
+
+		"POST /admin/login/2fa": {
+			RateLimit:     3.0 / 60.0,
+			TokenCapacity: 3.0,
+		},
+
+		"GET /login/sso/callback": {
+			RateLimit:     10.0 / 60.0,
+			TokenCapacity: 10.0,
+		},
+
+		"POST /account/password": {
+			RateLimit:     5.0 / 60.0,
+			TokenCapacity: 5.0,
+		},
+
 		"userpublic": {
 			RateLimit:     100.0 / 60.0,
 			TokenCapacity: 100.0,
@@ -67,7 +107,7 @@ func MiddlewareUserRateLimiter(next echo.HandlerFunc) echo.HandlerFunc {
 		var userLimiterConfig LimiterConfig
 		currentTime := time.Now().UnixMilli()
 
-		switch claims.Roles[0] {
+		switch claims.ActiveRole {
 		case USER_ROLE_ADMIN:
 			userLimiterConfig = rateLimiterConfig["useradmin"]
 		default: